@@ -0,0 +1,54 @@
+// Команда migrate применяет SQL-миграции из каталога на диске.
+//
+//	go run github.com/seemyown/backend-toolkit/btools/db/cmd/migrate -dir ./migrations -host localhost -user app -dbname app up
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/seemyown/backend-toolkit/btools/db"
+)
+
+func main() {
+	var (
+		dir   = flag.String("dir", "./migrations", "migrations directory")
+		table = flag.String("table", db.DefaultMigrationsTable, "migrations table")
+		cfg   db.Config
+	)
+	flag.StringVar(&cfg.Host, "host", env("PGHOST", "localhost"), "database host")
+	flag.StringVar(&cfg.Port, "port", env("PGPORT", "5432"), "database port")
+	flag.StringVar(&cfg.Username, "user", env("PGUSER", "postgres"), "database user")
+	flag.StringVar(&cfg.Password, "password", os.Getenv("PGPASSWORD"), "database password")
+	flag.StringVar(&cfg.Database, "dbname", env("PGDATABASE", "postgres"), "database name")
+	sslMode := flag.String("sslmode", env("PGSSLMODE", "disable"), "sslmode connection parameter")
+	flag.Parse()
+
+	cfg.Params = map[string]string{"sslmode": *sslMode}
+	conn := db.NewDatabase(&cfg)
+	err := run(conn, *dir, *table)
+	// Close, а не conn.DB.Close: он же останавливает проверку реплик.
+	// os.Exit не выполняет defer, поэтому закрываем явно
+	_ = conn.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(conn *db.Database, dir, table string) error {
+	migrator, err := db.NewMigrator(conn, os.DirFS(dir), db.MigratorConfig{Table: table})
+	if err != nil {
+		return err
+	}
+	return db.RunMigrateCommand(context.Background(), migrator, flag.Args(), os.Stdout)
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
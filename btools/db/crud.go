@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrConcurrentModification - исходная ошибка RepositoryError с кодом ErrCodeConcurrentModification.
+var ErrConcurrentModification = errors.New("row was modified concurrently")
+
+// execQuerier - то, через что BaseRepository пишет: *Database, *sqlx.DB или *sqlx.Tx.
+type execQuerier interface {
+	Querier
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type auditValues struct {
+	now      time.Time
+	actor    any
+	hasActor bool
+}
+
+func newAuditValues(ctx context.Context) auditValues {
+	actor, ok := ActorFromContext(ctx)
+	return auditValues{now: time.Now(), actor: actor, hasActor: ok}
+}
+
+// value возвращает значение служебной колонки; false - колонку нужно пропустить.
+func (a auditValues) value(name string, creating bool) (interface{}, bool) {
+	switch name {
+	case ColumnCreatedAt:
+		return a.now, creating
+	case ColumnUpdatedAt:
+		return a.now, true
+	case ColumnCreatedBy:
+		return a.actor, creating && a.hasActor
+	case ColumnUpdatedBy:
+		return a.actor, a.hasActor
+	}
+	return nil, false
+}
+
+func (m *tableMeta) insert(ctx context.Context, q execQuerier, entity interface{}) error {
+	v := reflect.ValueOf(entity).Elem()
+	audit := newAuditValues(ctx)
+
+	var cols, placeholders []string
+	var args []interface{}
+	for _, c := range m.columns {
+		var value interface{}
+		switch {
+		case m.softDelete && c.name == ColumnDeletedAt:
+			continue
+		case m.isAuditColumn(c.name):
+			val, ok := audit.value(c.name, true)
+			if !ok {
+				continue
+			}
+			value = val
+		case c.pk && v.FieldByIndex(c.index).IsZero():
+			// ключ генерирует база
+			continue
+		case c.version && v.FieldByIndex(c.index).IsZero():
+			value = 1
+		default:
+			value = v.FieldByIndex(c.index).Interface()
+		}
+		args = append(args, value)
+		cols = append(cols, c.name)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
+		m.table, strings.Join(cols, ", "), strings.Join(placeholders, ", "), m.selectList())
+	if err := instrument(q).GetContext(ctx, entity, query, args...); err != nil {
+		Logger.Error(err, "failed to insert into %s", m.table)
+		return WrapError(err)
+	}
+	return nil
+}
+
+func (m *tableMeta) get(ctx context.Context, q Querier, dest interface{}, key []interface{}) error {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", m.selectList(), m.table, m.where(ctx, 1))
+	if err := instrument(q).GetContext(ctx, dest, query, key...); err != nil {
+		Logger.Error(err, "failed to get from %s by %v", m.table, key)
+		return WrapError(err)
+	}
+	return nil
+}
+
+func (m *tableMeta) getAll(ctx context.Context, q Querier, dest interface{}) error {
+	query := fmt.Sprintf("SELECT %s FROM %s", m.selectList(), m.table)
+	if m.softDelete && !includeDeleted(ctx) {
+		query += " WHERE " + ColumnDeletedAt + " IS NULL"
+	}
+	if err := instrument(q).SelectContext(ctx, dest, query); err != nil {
+		Logger.Error(err, "failed to select from %s", m.table)
+		return WrapError(err)
+	}
+	return nil
+}
+
+func (m *tableMeta) update(ctx context.Context, q execQuerier, entity interface{}) error {
+	v := reflect.ValueOf(entity).Elem()
+	audit := newAuditValues(ctx)
+
+	var sets []string
+	var args []interface{}
+	for _, c := range m.columns {
+		var value interface{}
+		switch {
+		case c.pk, m.softDelete && c.name == ColumnDeletedAt:
+			continue
+		case c.version:
+			sets = append(sets, fmt.Sprintf("%s = %s + 1", c.name, c.name))
+			continue
+		case m.isAuditColumn(c.name):
+			val, ok := audit.value(c.name, false)
+			if !ok {
+				continue
+			}
+			value = val
+		default:
+			value = v.FieldByIndex(c.index).Interface()
+		}
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", c.name, len(args)))
+	}
+
+	where := m.where(ctx, len(args)+1)
+	key := m.pkArgs(v)
+	args = append(args, key...)
+	if m.version != nil {
+		args = append(args, v.FieldByIndex(m.version.index).Interface())
+		where += fmt.Sprintf(" AND %s = $%d", m.version.name, len(args))
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING %s",
+		m.table, strings.Join(sets, ", "), where, m.selectList())
+	q = instrument(q)
+	if err := q.GetContext(ctx, entity, query, args...); err != nil {
+		if m.version != nil && errors.Is(err, sql.ErrNoRows) {
+			return m.versionConflict(ctx, q, key)
+		}
+		Logger.Error(err, "failed to update %s", m.table)
+		return WrapError(err)
+	}
+	return nil
+}
+
+// versionConflict отличает удалённую строку от строки, изменённой параллельно.
+func (m *tableMeta) versionConflict(ctx context.Context, q Querier, key []interface{}) error {
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s)", m.table, m.where(ctx, 1))
+	if err := q.GetContext(ctx, &exists, query, key...); err != nil {
+		return WrapError(err)
+	}
+	if !exists {
+		return WrapError(sql.ErrNoRows)
+	}
+	Logger.Warn("concurrent modification of %s %v", m.table, key)
+	return newRepositoryError(concurrentModificationMapping, ErrConcurrentModification)
+}
+
+func (m *tableMeta) delete(ctx context.Context, q execQuerier, key []interface{}, hard bool) error {
+	var query string
+	var args []interface{}
+	if m.softDelete && !hard {
+		audit := newAuditValues(ctx)
+		args = append(args, audit.now)
+		sets := []string{ColumnDeletedAt + " = $1"}
+		for _, name := range []string{ColumnUpdatedAt, ColumnUpdatedBy} {
+			if !m.isAuditColumn(name) || !m.has(name) {
+				continue
+			}
+			if val, ok := audit.value(name, false); ok {
+				args = append(args, val)
+				sets = append(sets, fmt.Sprintf("%s = $%d", name, len(args)))
+			}
+		}
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", m.table, strings.Join(sets, ", "), m.where(ctx, len(args)+1))
+	} else {
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s", m.table, m.where(WithDeleted(ctx), 1))
+	}
+	args = append(args, key...)
+
+	result, err := instrument(q).ExecContext(ctx, query, args...)
+	if err != nil {
+		Logger.Error(err, "failed to delete from %s by %v", m.table, key)
+		return WrapError(err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return WrapError(sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Audit struct {
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	CreatedBy *int64     `db:"created_by"`
+}
+
+type testUser struct {
+	ID    int64  `db:"id"`
+	Email string `db:"email"`
+	Audit
+	secret string
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+// recordingQuerier запоминает последний запрос вместо обращения к базе
+type recordingQuerier struct {
+	query string
+	args  []interface{}
+	rows  int64
+}
+
+func (q *recordingQuerier) GetContext(_ context.Context, _ interface{}, query string, args ...interface{}) error {
+	q.query, q.args = query, args
+	return nil
+}
+
+func (q *recordingQuerier) SelectContext(_ context.Context, _ interface{}, query string, args ...interface{}) error {
+	q.query, q.args = query, args
+	return nil
+}
+
+func (q *recordingQuerier) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	q.query, q.args = query, args
+	return fakeResult(q.rows), nil
+}
+
+func (q *recordingQuerier) Rebind(query string) string {
+	return query
+}
+
+func newTestMeta(t *testing.T, opts ...RepositoryOption) *tableMeta {
+	options := repositoryOptions{}
+	for _, opt := range append([]RepositoryOption{WithTable("users")}, opts...) {
+		opt(&options)
+	}
+	meta, err := newTableMeta(reflect.TypeOf(testUser{}), options)
+	if err != nil {
+		t.Fatalf("newTableMeta вернула ошибку: %v", err)
+	}
+	return meta
+}
+
+func TestTableMeta(t *testing.T) {
+	meta := newTestMeta(t)
+	if len(meta.pk) != 1 || meta.pk[0].name != "id" {
+		t.Errorf("Ожидался первичный ключ id, а получили %+v", meta.pk)
+	}
+	expected := "id, email, created_at, updated_at, deleted_at, created_by"
+	if meta.selectList() != expected {
+		t.Errorf("Ожидался список колонок %q, а получили %q", expected, meta.selectList())
+	}
+
+	if _, err := newTableMeta(reflect.TypeOf(struct{ Name string }{}), repositoryOptions{table: "t"}); err == nil {
+		t.Errorf("Ожидалась ошибка для сущности без первичного ключа")
+	}
+}
+
+func TestCrudAudit(t *testing.T) {
+	meta := newTestMeta(t, WithAudit(), WithSoftDelete())
+	q := &recordingQuerier{}
+	ctx := WithActor(context.Background(), int64(7))
+
+	user := &testUser{Email: "a@b.c"}
+	if err := meta.insert(ctx, q, user); err != nil {
+		t.Fatalf("insert вернула ошибку: %v", err)
+	}
+	if !strings.HasPrefix(q.query, "INSERT INTO users (email, created_at, updated_at, created_by) VALUES ($1, $2, $3, $4) RETURNING") {
+		t.Errorf("Неверный INSERT: %s", q.query)
+	}
+	if q.args[3] != int64(7) {
+		t.Errorf("created_by должен браться из контекста, а получили %v", q.args[3])
+	}
+
+	user.ID = 1
+	if err := meta.update(ctx, q, user); err != nil {
+		t.Fatalf("update вернула ошибку: %v", err)
+	}
+	if !strings.HasPrefix(q.query, "UPDATE users SET email = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL RETURNING") {
+		t.Errorf("Неверный UPDATE: %s", q.query)
+	}
+}
+
+func TestCrudSoftDelete(t *testing.T) {
+	meta := newTestMeta(t, WithAudit(), WithSoftDelete())
+	q := &recordingQuerier{rows: 1}
+	ctx := context.Background()
+
+	if err := meta.delete(ctx, q, []interface{}{int64(1)}, false); err != nil {
+		t.Fatalf("delete вернула ошибку: %v", err)
+	}
+	if q.query != "UPDATE users SET deleted_at = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL" {
+		t.Errorf("Неверный мягкий DELETE: %s", q.query)
+	}
+
+	if err := meta.delete(ctx, q, []interface{}{int64(1)}, true); err != nil {
+		t.Fatalf("delete вернула ошибку: %v", err)
+	}
+	if q.query != "DELETE FROM users WHERE id = $1" {
+		t.Errorf("Неверный жёсткий DELETE: %s", q.query)
+	}
+
+	if err := meta.getAll(ctx, q, &[]*testUser{}); err != nil {
+		t.Fatalf("getAll вернула ошибку: %v", err)
+	}
+	if !strings.HasSuffix(q.query, "FROM users WHERE deleted_at IS NULL") {
+		t.Errorf("Чтение должно пропускать удалённые строки: %s", q.query)
+	}
+	if err := meta.getAll(WithDeleted(ctx), q, &[]*testUser{}); err != nil {
+		t.Fatalf("getAll вернула ошибку: %v", err)
+	}
+	if strings.Contains(q.query, "deleted_at IS NULL") {
+		t.Errorf("WithDeleted должен включать удалённые строки: %s", q.query)
+	}
+
+	q.rows = 0
+	err := meta.delete(ctx, q, []interface{}{int64(2)}, false)
+	if repoErr := WrapError(err); err == nil || repoErr.Code != ErrCodeNotFound {
+		t.Errorf("Ожидалась ошибка not found, а получили %v", err)
+	}
+}
+
+type versionedDoc struct {
+	ID      int64  `db:"id"`
+	Title   string `db:"title"`
+	Version int64  `db:"version" version:"true"`
+}
+
+// conflictQuerier имитирует UPDATE, не нашедший строку с нужной версией
+type conflictQuerier struct {
+	recordingQuerier
+	exists bool
+}
+
+func (q *conflictQuerier) GetContext(_ context.Context, dest interface{}, query string, args ...interface{}) error {
+	if strings.HasPrefix(query, "UPDATE") {
+		q.query, q.args = query, args
+		return sql.ErrNoRows
+	}
+	*(dest.(*bool)) = q.exists
+	return nil
+}
+
+func TestCrudOptimisticLock(t *testing.T) {
+	meta, err := newTableMeta(reflect.TypeOf(versionedDoc{}), repositoryOptions{table: "docs"})
+	if err != nil {
+		t.Fatalf("newTableMeta вернула ошибку: %v", err)
+	}
+	ctx := context.Background()
+
+	q := &conflictQuerier{exists: true}
+	err = meta.update(ctx, q, &versionedDoc{ID: 1, Title: "draft", Version: 3})
+	if q.query != "UPDATE docs SET title = $1, version = version + 1 WHERE id = $2 AND version = $3 RETURNING id, title, version" {
+		t.Errorf("Неверный UPDATE с версией: %s", q.query)
+	}
+	if q.args[2] != int64(3) {
+		t.Errorf("В условие должна попасть текущая версия, а получили %v", q.args[2])
+	}
+	repoErr := WrapError(err)
+	if repoErr == nil || repoErr.Code != ErrCodeConcurrentModification || repoErr.HTTPStatus() != 409 {
+		t.Errorf("Ожидалась ошибка параллельного изменения, а получили %v", err)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Параллельное изменение не должно выглядеть как sql.ErrNoRows")
+	}
+
+	q.exists = false
+	err = meta.update(ctx, q, &versionedDoc{ID: 2, Version: 1})
+	if repoErr := WrapError(err); repoErr == nil || repoErr.Code != ErrCodeNotFound {
+		t.Errorf("Для удалённой строки ожидалась ошибка not found, а получили %v", err)
+	}
+
+	insert := &recordingQuerier{}
+	if err := meta.insert(ctx, insert, &versionedDoc{Title: "new"}); err != nil {
+		t.Fatalf("insert вернула ошибку: %v", err)
+	}
+	if insert.args[1] != 1 {
+		t.Errorf("Новая запись должна получить версию 1, а получили %v", insert.args[1])
+	}
+}
+
+type orderItem struct {
+	OrderID  int64 `db:"order_id" pk:"true"`
+	ItemID   int64 `db:"item_id" pk:"true"`
+	Quantity int   `db:"quantity"`
+}
+
+type orderItemKey struct {
+	ItemID  int64 `db:"item_id"`
+	OrderID int64 `db:"order_id"`
+}
+
+var _ Int64Repository[testUser] = (*BaseRepository[testUser, int64])(nil)
+var _ Repository[orderItem, orderItemKey] = (*BaseRepository[orderItem, orderItemKey])(nil)
+
+func TestCompositeKey(t *testing.T) {
+	meta, err := newTableMeta(reflect.TypeOf(orderItem{}), repositoryOptions{table: "order_items"})
+	if err != nil {
+		t.Fatalf("newTableMeta вернула ошибку: %v", err)
+	}
+	if err := meta.bindKey(reflect.TypeOf(int64(0))); err == nil {
+		t.Errorf("Скалярный ключ не подходит для составного первичного ключа")
+	}
+	if err := meta.bindKey(reflect.TypeOf(orderItemKey{})); err != nil {
+		t.Fatalf("bindKey вернула ошибку: %v", err)
+	}
+
+	q := &recordingQuerier{rows: 1}
+	key := orderItemKey{OrderID: 10, ItemID: 20}
+	if err := meta.delete(context.Background(), q, meta.keyArgs(key), false); err != nil {
+		t.Fatalf("delete вернула ошибку: %v", err)
+	}
+	if q.query != "DELETE FROM order_items WHERE order_id = $1 AND item_id = $2" {
+		t.Errorf("Неверный DELETE по составному ключу: %s", q.query)
+	}
+	if q.args[0] != int64(10) || q.args[1] != int64(20) {
+		t.Errorf("Аргументы ключа должны идти в порядке колонок pk, а получили %v", q.args)
+	}
+}
+
+func TestScalarKey(t *testing.T) {
+	meta := newTestMeta(t)
+	if err := meta.bindKey(reflect.TypeOf("")); err != nil {
+		t.Fatalf("bindKey вернула ошибку: %v", err)
+	}
+	if args := meta.keyArgs("6f1c"); len(args) != 1 || args[0] != "6f1c" {
+		t.Errorf("Скалярный ключ должен передаваться как есть, а получили %v", args)
+	}
+
+	// значения драйвера - не структуры-ключи, даже если это структуры
+	for _, key := range []any{sql.NullInt64{Int64: 7, Valid: true}, time.Unix(0, 0)} {
+		meta := newTestMeta(t)
+		if err := meta.bindKey(reflect.TypeOf(key)); err != nil {
+			t.Fatalf("bindKey для %T вернула ошибку: %v", key, err)
+		}
+		if args := meta.keyArgs(key); len(args) != 1 || args[0] != key {
+			t.Errorf("Ключ %T должен передаваться как есть, а получили %v", key, args)
+		}
+	}
+
+	// структура-ключ для одной колонки разбирается по тегам db
+	type userKey struct {
+		ID int64 `db:"id"`
+	}
+	meta = newTestMeta(t)
+	if err := meta.bindKey(reflect.TypeOf(userKey{})); err != nil {
+		t.Fatalf("bindKey вернула ошибку: %v", err)
+	}
+	if args := meta.keyArgs(userKey{ID: 5}); len(args) != 1 || args[0] != int64(5) {
+		t.Errorf("Ожидалось значение поля id, а получили %v", args)
+	}
+
+	type wrongKey struct {
+		UserID int64 `db:"user_id"`
+	}
+	if err := newTestMeta(t).bindKey(reflect.TypeOf(wrongKey{})); err == nil {
+		t.Errorf("Ожидалась ошибка для структуры без поля первичного ключа")
+	}
+}
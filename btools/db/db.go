@@ -2,11 +2,16 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/seemyown/backend-toolkit/btools/exc"
 	"github.com/seemyown/backend-toolkit/btools/logging"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var log = logging.New(logging.Config{
@@ -21,6 +26,17 @@ type Config struct {
 	Password string
 	Database string
 	Params   map[string]string
+	// Driver - драйвер подключения: DriverPQ (по умолчанию) или DriverPgx.
+	Driver Driver
+	// Replicas - хосты реплик для чтения в формате "host" или "host:port".
+	// Остальные параметры подключения берутся из основного конфига.
+	Replicas []string
+	// ReplicaHealthCheckInterval - период проверки доступности реплик (по умолчанию 5s).
+	ReplicaHealthCheckInterval time.Duration
+	// Hooks - хуки инструментирования запросов, см. QueryHook.
+	Hooks []QueryHook
+	// Tenancy - переключение арендатора из контекста, см. WithTenant. По умолчанию выключено.
+	Tenancy *TenancyConfig
 }
 
 func (c *Config) String() string {
@@ -36,39 +52,160 @@ func (c *Config) String() string {
 	return baseConnString
 }
 
+func (c *Config) replica(host string) *Config {
+	replicaCfg := *c
+	replicaCfg.Host = host
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		replicaCfg.Host = h
+		replicaCfg.Port = p
+	}
+	replicaCfg.Replicas = nil
+	return &replicaCfg
+}
+
+// Querier - общий интерфейс для чтения, который реализуют *sqlx.DB, *sqlx.Tx и *Database.
+type Querier interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	Rebind(query string) string
+}
+
+// Database - подключение к основному серверу (DB) и, опционально, к репликам.
+// Чтение через методы Database уходит на реплики, запись и транзакции - на основной сервер.
 type Database struct {
 	DB *sqlx.DB
+
+	dsn       string
+	replicas  []*replica
+	hooks     []QueryHook
+	tenancy   *TenancyConfig
+	next      atomic.Uint64
+	stopCheck chan struct{}
+	closeOnce sync.Once
 }
 
 func NewDatabase(cfg *Config) *Database {
-	conn, err := sqlx.Connect("postgres", cfg.String())
+	conn, err := sqlx.Connect(cfg.driverName(), cfg.String())
 	if err != nil {
 		log.Error(err, "error connecting to database")
 		panic(err)
 	}
-	return &Database{conn}
+	d := &Database{DB: conn, dsn: cfg.String(), hooks: cfg.Hooks, tenancy: cfg.Tenancy}
+	databases.Store(conn, d)
+	d.connectReplicas(cfg)
+	return d
+}
+
+// NewDatabaseFromDB оборачивает уже открытое подключение без реплик (например, из db/dbtest).
+// Listen для такого Database недоступен: у него нет строки подключения.
+func NewDatabaseFromDB(conn *sqlx.DB, hooks ...QueryHook) *Database {
+	d := &Database{DB: conn, hooks: hooks}
+	databases.Store(conn, d)
+	return d
+}
+
+// Reader возвращает подключение для чтения: следующую здоровую реплику по кругу
+// или основной сервер, если реплик нет, все они недоступны или в контексте стоит WithPrimary.
+func (d *Database) Reader(ctx context.Context) *sqlx.DB {
+	if len(d.replicas) == 0 || UsesPrimary(ctx) {
+		return d.DB
+	}
+	n := uint64(len(d.replicas))
+	start := d.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := d.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return d.DB
+}
+
+func (d *Database) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return d.observe(ctx, QueryKindQuery, query, args, func(ctx context.Context) (int64, error) {
+		err := d.withTenant(ctx, d.Reader(ctx), func(q sqlx.ExtContext) error {
+			return sqlx.GetContext(ctx, q, dest, query, args...)
+		})
+		if err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+}
+
+func (d *Database) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return d.observe(ctx, QueryKindQuery, query, args, func(ctx context.Context) (int64, error) {
+		err := d.withTenant(ctx, d.Reader(ctx), func(q sqlx.ExtContext) error {
+			return sqlx.SelectContext(ctx, q, dest, query, args...)
+		})
+		if err != nil {
+			return 0, err
+		}
+		return destLen(dest), nil
+	})
+}
+
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := d.observe(ctx, QueryKindExec, query, args, func(ctx context.Context) (int64, error) {
+		err := d.withTenant(ctx, d.DB, func(q sqlx.ExtContext) error {
+			var err error
+			result, err = q.ExecContext(ctx, query, args...)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return -1, nil
+		}
+		return rows, nil
+	})
+	return result, err
+}
+
+func (d *Database) Rebind(query string) string {
+	return d.DB.Rebind(query)
+}
+
+// Close останавливает проверку реплик и закрывает все подключения.
+func (d *Database) Close() error {
+	d.closeOnce.Do(func() {
+		if d.stopCheck != nil {
+			close(d.stopCheck)
+		}
+	})
+	databases.Delete(d.DB)
+	for _, r := range d.replicas {
+		databases.Delete(r.db)
+		if err := r.db.Close(); err != nil {
+			log.Error(err, "error closing replica %s", r.host)
+		}
+	}
+	return d.DB.Close()
 }
 
-func SelectOne[T any](db *sqlx.DB, ctx context.Context, query string, args ...interface{}) (*T, error) {
+func SelectOne[T any](db Querier, ctx context.Context, query string, args ...interface{}) (*T, error) {
 	var result T
-	if err := db.GetContext(ctx, &result, query, args...); err != nil {
+	if err := instrument(db).GetContext(ctx, &result, query, args...); err != nil {
 		Logger.Error(err, "failed to execute query %s, %v", query, args)
 		return nil, WrapError(err)
 	}
 	return &result, nil
 }
 
-func SelectMany[T any](db *sqlx.DB, ctx context.Context, query string, args ...interface{}) ([]*T, error) {
+func SelectMany[T any](db Querier, ctx context.Context, query string, args ...interface{}) ([]*T, error) {
 	var result []*T
 
-	if err := db.SelectContext(ctx, &result, query, args...); err != nil {
+	if err := instrument(db).SelectContext(ctx, &result, query, args...); err != nil {
 		Logger.Error(err, "failed to execute query %s, %v", query, args)
 		return nil, WrapError(err)
 	}
 	return result, nil
 }
 
-func SelectIn[T any](ctx context.Context, db *sqlx.DB, dest *[]T, query string, inArgs any) error {
+func SelectIn[T any](ctx context.Context, db Querier, dest *[]T, query string, inArgs any) error {
 	q, args, err := sqlx.In(query, inArgs)
 	if err != nil {
 		return exc.RepositoryError(err.Error())
@@ -76,7 +213,7 @@ func SelectIn[T any](ctx context.Context, db *sqlx.DB, dest *[]T, query string,
 
 	q = db.Rebind(q)
 
-	if err := db.SelectContext(ctx, dest, q, args...); err != nil {
+	if err := instrument(db).SelectContext(ctx, dest, q, args...); err != nil {
 		return WrapError(err)
 	}
 
@@ -91,8 +228,17 @@ func PrepareAndExec[T any](ctx context.Context, tx *sqlx.Tx, query string, items
 	}
 	defer func() { _ = stmt.Close() }()
 
+	// аргументы знает только execFn, поэтому хуки получают запрос без них
+	exec := execFn
+	if d := owner(tx); d != nil {
+		exec = func(stmt *sqlx.Stmt, item *T) error {
+			return d.observe(ctx, QueryKindExec, query, nil, func(context.Context) (int64, error) {
+				return -1, execFn(stmt, item)
+			})
+		}
+	}
 	for _, item := range items {
-		if err := execFn(stmt, item); err != nil {
+		if err := exec(stmt, item); err != nil {
 			return WrapError(err)
 		}
 	}
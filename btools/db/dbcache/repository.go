@@ -0,0 +1,164 @@
+// Пакет dbcache - кэширующий декоратор репозиториев поверх store.Store.
+package dbcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+	"github.com/seemyown/backend-toolkit/btools/db"
+	"github.com/seemyown/backend-toolkit/btools/logging"
+	"github.com/seemyown/backend-toolkit/btools/store"
+	"golang.org/x/sync/singleflight"
+)
+
+var log = logging.New(logging.Config{
+	FileName: "repository",
+	Name:     "cache",
+})
+
+type Config[T any, K comparable] struct {
+	// Prefix - префикс ключей в хранилище, например "users"
+	Prefix string
+	// TTL - время жизни записи в кэше
+	TTL time.Duration
+	// KeyFunc возвращает первичный ключ сущности; нужен для инвалидации в Update
+	KeyFunc func(entity *T) K
+}
+
+// ErrUnmanagedTx - UpdateTx/DeleteTx получили транзакцию, открытую не через db.Transaction.
+// Для такой транзакции нельзя сбросить кэш после коммита, поэтому запись не выполняется.
+var ErrUnmanagedTx = errors.New("dbcache: transaction is not managed by db.Transaction")
+
+// Repository кэширует Get с TTL и сбрасывает кэш после Update/Delete.
+// Для *Tx методов кэш сбрасывается только после коммита транзакции (см. db.AfterCommit),
+// поэтому они принимают только транзакции db.Transaction, иначе возвращают ErrUnmanagedTx.
+// Одновременные промахи по одному ключу схлопываются в один запрос к базе. Промах читается
+// с основного сервера (db.WithPrimary): запись с отстающей реплики осталась бы в кэше на весь TTL.
+type Repository[T any, K comparable] struct {
+	repo   db.Repository[T, K]
+	store  store.Store
+	config Config[T, K]
+	group  singleflight.Group
+}
+
+var _ db.Repository[struct{}, int64] = (*Repository[struct{}, int64])(nil)
+
+func New[T any, K comparable](repo db.Repository[T, K], s store.Store, config Config[T, K]) *Repository[T, K] {
+	if config.KeyFunc == nil {
+		panic("dbcache: Config.KeyFunc is required")
+	}
+	return &Repository[T, K]{repo: repo, store: s, config: config}
+}
+
+func (r *Repository[T, K]) key(id K) string {
+	return fmt.Sprintf("%s:%v", r.config.Prefix, id)
+}
+
+func (r *Repository[T, K]) Get(ctx context.Context, id K) (*T, error) {
+	key := r.key(id)
+
+	var cached T
+	err := r.store.Get(ctx, key, &cached)
+	if err == nil {
+		return &cached, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		log.Error(err, "failed to read %s from cache", key)
+	}
+
+	shared, err, _ := r.group.Do(key, func() (interface{}, error) {
+		// запрос общий для всех ожидающих, поэтому отмена контекста первого из них
+		// не должна ронять остальных; читаем с основного сервера, чтобы не закэшировать
+		// устаревшую запись с реплики
+		ctx := db.WithPrimary(context.WithoutCancel(ctx))
+		// пока мы ждали своей очереди, предыдущий запрос мог уже положить запись в кэш
+		var cached T
+		if err := r.store.Get(ctx, key, &cached); err == nil {
+			return &cached, nil
+		}
+		entity, err := r.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.store.Set(ctx, key, entity, r.config.TTL); err != nil {
+			log.Error(err, "failed to write %s to cache", key)
+		}
+		return entity, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// результат singleflight общий для всех ожидавших - отдаём каждому свою копию
+	entity := *shared.(*T)
+	return &entity, nil
+}
+
+func (r *Repository[T, K]) Create(ctx context.Context, entity *T) error {
+	return r.repo.Create(ctx, entity)
+}
+
+func (r *Repository[T, K]) CreateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error {
+	return r.repo.CreateTx(ctx, tx, entity)
+}
+
+func (r *Repository[T, K]) Update(ctx context.Context, entity *T) error {
+	if err := r.repo.Update(ctx, entity); err != nil {
+		return err
+	}
+	r.Invalidate(ctx, r.config.KeyFunc(entity))
+	return nil
+}
+
+func (r *Repository[T, K]) UpdateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error {
+	if err := r.invalidateAfterCommit(ctx, tx, r.config.KeyFunc(entity)); err != nil {
+		return err
+	}
+	return r.repo.UpdateTx(ctx, tx, entity)
+}
+
+func (r *Repository[T, K]) Delete(ctx context.Context, id K) error {
+	if err := r.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.Invalidate(ctx, id)
+	return nil
+}
+
+func (r *Repository[T, K]) DeleteTx(ctx context.Context, tx *sqlx.Tx, id K) error {
+	if err := r.invalidateAfterCommit(ctx, tx, id); err != nil {
+		return err
+	}
+	return r.repo.DeleteTx(ctx, tx, id)
+}
+
+func (r *Repository[T, K]) GetAll(ctx context.Context, args ...interface{}) ([]*T, error) {
+	return r.repo.GetAll(ctx, args...)
+}
+
+func (r *Repository[T, K]) Search(ctx context.Context, args ...interface{}) ([]*T, error) {
+	return r.repo.Search(ctx, args...)
+}
+
+// Invalidate удаляет сущность из кэша.
+func (r *Repository[T, K]) Invalidate(ctx context.Context, id K) {
+	key := r.key(id)
+	if err := r.store.Delete(ctx, key); err != nil {
+		log.Error(err, "failed to invalidate %s", key)
+	}
+}
+
+// invalidateAfterCommit регистрирует сброс кэша до записи: если запись не удастся, транзакция
+// откатится и хук не сработает. Сбросить кэш до коммита нельзя - параллельный Get успел бы
+// положить в кэш старую запись до истечения TTL.
+func (r *Repository[T, K]) invalidateAfterCommit(ctx context.Context, tx *sqlx.Tx, id K) error {
+	// контекст запроса к моменту коммита может быть уже отменён
+	ctx = context.WithoutCancel(ctx)
+	if !db.AfterCommit(tx, func() { r.Invalidate(ctx, id) }) {
+		return ErrUnmanagedTx
+	}
+	return nil
+}
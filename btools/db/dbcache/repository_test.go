@@ -0,0 +1,299 @@
+package dbcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+	"github.com/seemyown/backend-toolkit/btools/db"
+)
+
+type user struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// memoryStore - store.Store в памяти
+type memoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	// misses получает сигнал на каждый промах, если канал задан
+	misses chan struct{}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{data: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, value any, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = data
+	return nil
+}
+
+func (s *memoryStore) Get(_ context.Context, key string, dest any) error {
+	s.mu.Lock()
+	data, ok := s.data[key]
+	s.mu.Unlock()
+	if !ok {
+		if s.misses != nil {
+			s.misses <- struct{}{}
+		}
+		return redis.Nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (s *memoryStore) Keys(context.Context, string) ([]string, error)        { return nil, nil }
+func (s *memoryStore) Scan(context.Context, string, int64) ([]string, error) { return nil, nil }
+func (s *memoryStore) Pipeline() redis.Pipeliner                             { return nil }
+
+func (s *memoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
+
+func (s *memoryStore) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.data[key]
+	return ok
+}
+
+// countingRepo считает обращения к "базе" и держит запрос, пока не закрыт release
+type countingRepo struct {
+	db.Int64BaseRepository[user]
+	gets    atomic.Int32
+	replica atomic.Int32 // чтения без db.WithPrimary
+	release chan struct{}
+}
+
+func (r *countingRepo) Get(ctx context.Context, id int64) (*user, error) {
+	r.gets.Add(1)
+	if !db.UsesPrimary(ctx) {
+		r.replica.Add(1)
+	}
+	if r.release != nil {
+		<-r.release
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &user{ID: id, Name: "Alice"}, nil
+}
+
+func (r *countingRepo) Update(context.Context, *user) error             { return nil }
+func (r *countingRepo) UpdateTx(context.Context, *sqlx.Tx, *user) error { return nil }
+func (r *countingRepo) Delete(context.Context, int64) error             { return nil }
+func (r *countingRepo) DeleteTx(context.Context, *sqlx.Tx, int64) error { return nil }
+
+func newTestRepo(repo *countingRepo, s *memoryStore) *Repository[user, int64] {
+	return New[user, int64](repo, s, Config[user, int64]{
+		Prefix:  "users",
+		TTL:     time.Minute,
+		KeyFunc: func(u *user) int64 { return u.ID },
+	})
+}
+
+func TestRepositoryGetCaches(t *testing.T) {
+	s := newMemoryStore()
+	inner := &countingRepo{}
+	repo := newTestRepo(inner, s)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		u, err := repo.Get(ctx, 1)
+		if err != nil {
+			t.Fatalf("Get вернул ошибку: %v", err)
+		}
+		if u.Name != "Alice" {
+			t.Errorf("Ожидалось имя Alice, а получили %s", u.Name)
+		}
+	}
+	if inner.gets.Load() != 1 {
+		t.Errorf("Ожидалось одно обращение к базе, а было %d", inner.gets.Load())
+	}
+	if inner.replica.Load() != 0 {
+		t.Errorf("Промах кэша должен читаться с основного сервера")
+	}
+	if !s.has("users:1") {
+		t.Errorf("Запись должна лежать в кэше под ключом users:1")
+	}
+}
+
+func TestRepositorySingleFlight(t *testing.T) {
+	const callers = 10
+	s := newMemoryStore()
+	s.misses = make(chan struct{}, 2*callers)
+	inner := &countingRepo{release: make(chan struct{})}
+	repo := newTestRepo(inner, s)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]*user, callers)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = repo.Get(ctx, 1)
+		}(i)
+	}
+	// все вызывающие промахнулись мимо кэша, и только после этого "база" отвечает.
+	// Кто не успел встать в ожидание singleflight, найдёт запись в кэше внутри Do.
+	for range callers {
+		<-s.misses
+	}
+	close(inner.release)
+	wg.Wait()
+
+	if inner.gets.Load() != 1 {
+		t.Errorf("Одновременные промахи должны дать один запрос к базе, а было %d", inner.gets.Load())
+	}
+	if results[0] == nil || results[0] == results[1] {
+		t.Errorf("Каждый вызывающий должен получить свою копию сущности")
+	}
+}
+
+func TestRepositorySingleFlightCancel(t *testing.T) {
+	s := newMemoryStore()
+	s.misses = make(chan struct{}, 2)
+	inner := &countingRepo{release: make(chan struct{})}
+	repo := newTestRepo(inner, s)
+
+	// запрос к базе общий для всех, кто ждёт этот ключ, поэтому отмена запроса того,
+	// кто его начал, не должна до него доходить - иначе ошибку получат все ожидающие
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := repo.Get(ctx, 1)
+		done <- err
+	}()
+	<-s.misses
+	cancel()
+	close(inner.release)
+
+	if err := <-done; err != nil {
+		t.Errorf("Запрос к базе не должен видеть отмену контекста вызывающего: %v", err)
+	}
+}
+
+func TestRepositoryInvalidation(t *testing.T) {
+	s := newMemoryStore()
+	repo := newTestRepo(&countingRepo{}, s)
+	ctx := context.Background()
+
+	if _, err := repo.Get(ctx, 1); err != nil {
+		t.Fatalf("Get вернул ошибку: %v", err)
+	}
+	if err := repo.Update(ctx, &user{ID: 1, Name: "Bob"}); err != nil {
+		t.Fatalf("Update вернул ошибку: %v", err)
+	}
+	if s.has("users:1") {
+		t.Errorf("Update должен сбрасывать кэш")
+	}
+
+	if _, err := repo.Get(ctx, 1); err != nil {
+		t.Fatalf("Get вернул ошибку: %v", err)
+	}
+	// транзакция не открыта через db.Transaction - сбросить кэш после коммита нельзя
+	if err := repo.DeleteTx(ctx, &sqlx.Tx{}, 1); !errors.Is(err, ErrUnmanagedTx) {
+		t.Errorf("Ожидалась ErrUnmanagedTx, а получили %v", err)
+	}
+	if !s.has("users:1") {
+		t.Errorf("Кэш не должен сбрасываться до коммита")
+	}
+}
+
+// fakeDriver - драйвер без запросов, только с транзакциями, для db.Transaction
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeTx struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("dbcache-fake", fakeDriver{})
+}
+
+func newTestTrx(t *testing.T) db.Transaction {
+	conn, err := sqlx.Open("dbcache-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return db.NewTrx(db.NewDatabaseFromDB(conn))
+}
+
+func TestRepositoryInvalidationAfterCommit(t *testing.T) {
+	s := newMemoryStore()
+	repo := newTestRepo(&countingRepo{}, s)
+	ctx := context.Background()
+	if _, err := repo.Get(ctx, 1); err != nil {
+		t.Fatalf("Get вернул ошибку: %v", err)
+	}
+
+	err := newTestTrx(t).Exec(ctx, func(tx *sqlx.Tx) error {
+		if err := repo.UpdateTx(ctx, tx, &user{ID: 1, Name: "Bob"}); err != nil {
+			return err
+		}
+		if !s.has("users:1") {
+			t.Errorf("Кэш не должен сбрасываться до коммита")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Транзакция завершилась ошибкой: %v", err)
+	}
+	if s.has("users:1") {
+		t.Errorf("После коммита кэш должен быть сброшен")
+	}
+}
+
+func TestRepositoryInvalidationRollback(t *testing.T) {
+	s := newMemoryStore()
+	repo := newTestRepo(&countingRepo{}, s)
+	ctx := context.Background()
+	if _, err := repo.Get(ctx, 1); err != nil {
+		t.Fatalf("Get вернул ошибку: %v", err)
+	}
+
+	rollback := errors.New("rollback")
+	err := newTestTrx(t).Exec(ctx, func(tx *sqlx.Tx) error {
+		if err := repo.DeleteTx(ctx, tx, 1); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Ожидалась ошибка из транзакции, а получили %v", err)
+	}
+	if !s.has("users:1") {
+		t.Errorf("После отката кэш не должен сбрасываться")
+	}
+}
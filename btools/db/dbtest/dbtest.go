@@ -0,0 +1,157 @@
+// Package dbtest поднимает Postgres для тестов репозиториев без общей dev-базы.
+//
+// Подключение берётся из TEST_DATABASE_URL, а если он не задан - Main запускает временный
+// сервер из локально установленных бинарников. Каждый тест получает свою схему с накатанными
+// миграциями внутри транзакции, которая откатывается по завершении теста:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(dbtest.Main(m))
+//	}
+//
+//	func TestUserRepository(t *testing.T) {
+//		conn := dbtest.New(t, dbtest.Config{Migrations: migrations.FS})
+//		repo := NewUserRepository(conn)
+//		...
+//	}
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/seemyown/backend-toolkit/btools/db"
+)
+
+// EnvDatabaseURL - переменная окружения с подключением к уже запущенному Postgres.
+const EnvDatabaseURL = "TEST_DATABASE_URL"
+
+var (
+	serverURL string
+	serverErr error
+)
+
+type Config struct {
+	// Migrations - миграции, которые накатываются в схему теста (см. db.Migrator).
+	Migrations fs.FS
+	// Migrator - каталог и таблица миграций.
+	Migrator db.MigratorConfig
+	// Driver - драйвер подключения (по умолчанию db.DriverPQ).
+	Driver db.Driver
+	// Hooks - хуки инструментирования запросов.
+	Hooks []db.QueryHook
+}
+
+// Main запускает временный Postgres, если не задан TEST_DATABASE_URL, выполняет тесты
+// пакета и останавливает сервер. Вызывается из TestMain. Если Postgres поднять не удалось,
+// тесты с New пропускаются; если не удалось остановить - ошибка пишется в stderr,
+// а код выхода становится ненулевым.
+func Main(m *testing.M) int {
+	if os.Getenv(EnvDatabaseURL) != "" {
+		return m.Run()
+	}
+
+	server, err := StartServer()
+	if err != nil {
+		serverErr = err
+		return m.Run()
+	}
+	serverURL = server.URL
+	code := m.Run()
+	if err := server.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "dbtest: stop postgres: %v\n", err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// New возвращает подключение к отдельной схеме с накатанными миграциями. Всё, что сделает тест,
+// включая саму схему, откатывается в t.Cleanup. Транзакции тестируемого кода превращаются
+// в savepoint-ы, поэтому db.Transaction и репозитории работают как обычно.
+//
+// Все соединения пула делят одно физическое соединение: LISTEN, реплики и параллельные
+// транзакции внутри одного теста не поддерживаются.
+func New(t testing.TB, cfg Config) *db.Database {
+	t.Helper()
+
+	dsn := databaseURL(t)
+	driverName := string(cfg.Driver)
+	if driverName == "" {
+		driverName = string(db.DriverPQ)
+	}
+	ctx := context.Background()
+
+	pool, err := sql.Open(driverName, dsn)
+	if err != nil {
+		t.Fatalf("dbtest: open driver %s: %v", driverName, err)
+	}
+	drv := pool.Driver()
+	_ = pool.Close()
+
+	raw, err := drv.Open(dsn)
+	if err != nil {
+		t.Fatalf("dbtest: connect: %v", err)
+	}
+	sess, err := newSession(ctx, raw)
+	if err != nil {
+		t.Fatalf("dbtest: begin test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := sess.rollback(); err != nil {
+			t.Errorf("dbtest: rollback test transaction: %v", err)
+		}
+	})
+
+	// отдельная схема нужна параллельным тестам: иначе одноимённые таблицы
+	// из незакоммиченных транзакций блокировали бы друг друга
+	schema := "test_" + randomSuffix()
+	if _, err := sess.exec(ctx, "CREATE SCHEMA "+schema, nil); err != nil {
+		t.Fatalf("dbtest: create schema: %v", err)
+	}
+	if _, err := sess.exec(ctx, "SET search_path TO "+schema, nil); err != nil {
+		t.Fatalf("dbtest: set search_path: %v", err)
+	}
+
+	sqlDB := sql.OpenDB(&connector{session: sess, driver: drv})
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	conn := db.NewDatabaseFromDB(sqlx.NewDb(sqlDB, driverName), cfg.Hooks...)
+
+	if cfg.Migrations != nil {
+		migrator, err := db.NewMigrator(conn, cfg.Migrations, cfg.Migrator)
+		if err != nil {
+			t.Fatalf("dbtest: load migrations: %v", err)
+		}
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatalf("dbtest: apply migrations: %v", err)
+		}
+	}
+	return conn
+}
+
+func databaseURL(t testing.TB) string {
+	if url := os.Getenv(EnvDatabaseURL); url != "" {
+		return url
+	}
+	if serverURL != "" {
+		return serverURL
+	}
+	if serverErr != nil {
+		t.Skipf("dbtest: postgres is not available: %v", serverErr)
+	}
+	t.Skipf("dbtest: set %s or call dbtest.Main from TestMain", EnvDatabaseURL)
+	return ""
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,239 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/seemyown/backend-toolkit/btools/db"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(Main(m))
+}
+
+// fakeConn запоминает выполненные запросы и на любой SELECT отдаёт одну строку.
+// Запрос fail завершается ошибкой и, как в Postgres, ломает транзакцию до ROLLBACK TO SAVEPOINT.
+type fakeConn struct {
+	queries []string
+	aborted bool
+}
+
+var errAborted = errors.New("current transaction is aborted")
+
+func (c *fakeConn) run(query string) error {
+	c.queries = append(c.queries, query)
+	switch {
+	case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT"):
+		c.aborted = false
+	case c.aborted:
+		return errAborted
+	case query == "fail":
+		c.aborted = true
+		return errors.New("duplicate key value violates unique constraint")
+	}
+	return nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{c}, nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.run(query); err != nil {
+		return nil, err
+	}
+	return &bufferedRows{columns: []string{"name"}, rows: [][]driver.Value{{[]byte("alice")}}}, nil
+}
+
+type fakeTx struct{ c *fakeConn }
+
+func (tx fakeTx) Commit() error {
+	tx.c.queries = append(tx.c.queries, "COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.c.queries = append(tx.c.queries, "ROLLBACK")
+	return nil
+}
+
+func TestSessionSavepoints(t *testing.T) {
+	raw := &fakeConn{}
+	sess, err := newSession(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("newSession вернула ошибку: %v", err)
+	}
+	sqlDB := sql.OpenDB(&connector{session: sess})
+	defer sqlDB.Close()
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatalf("Begin вернул ошибку: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO users DEFAULT VALUES"); err != nil {
+		t.Fatalf("Exec вернул ошибку: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit вернул ошибку: %v", err)
+	}
+
+	tx, _ = sqlDB.Begin()
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback вернул ошибку: %v", err)
+	}
+
+	var name string
+	if err := sqlDB.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "alice" {
+		t.Errorf("Ожидалась строка alice, а получили %q, %v", name, err)
+	}
+
+	if err := sess.rollback(); err != nil {
+		t.Fatalf("rollback вернул ошибку: %v", err)
+	}
+	expected := []string{
+		"SAVEPOINT dbtest_1",
+		"INSERT INTO users DEFAULT VALUES",
+		"RELEASE SAVEPOINT dbtest_1",
+		"SAVEPOINT dbtest_2",
+		"ROLLBACK TO SAVEPOINT dbtest_2",
+		"SAVEPOINT dbtest_stmt",
+		"SELECT name FROM users",
+		"RELEASE SAVEPOINT dbtest_stmt",
+		"ROLLBACK",
+	}
+	if !reflect.DeepEqual(raw.queries, expected) {
+		t.Errorf("Ожидались запросы %v, а получили %v", expected, raw.queries)
+	}
+
+	if _, err := sqlDB.Exec("SELECT 1"); err == nil {
+		t.Errorf("После отката соединение должно быть недоступно")
+	}
+}
+
+func TestSessionStatementError(t *testing.T) {
+	raw := &fakeConn{}
+	sess, err := newSession(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("newSession вернула ошибку: %v", err)
+	}
+	sqlDB := sql.OpenDB(&connector{session: sess})
+	defer sqlDB.Close()
+
+	// ошибка запроса вне транзакции не должна ломать транзакцию теста
+	if _, err := sqlDB.Exec("fail"); err == nil {
+		t.Fatal("Ожидалась ошибка запроса")
+	}
+	var name string
+	if err := sqlDB.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "alice" {
+		t.Errorf("После ошибки запросы должны работать, а получили %q, %v", name, err)
+	}
+
+	// внутри транзакции тестируемого кода ошибку откатывает сам код
+	tx, _ := sqlDB.Begin()
+	if _, err := tx.Exec("fail"); err == nil {
+		t.Fatal("Ожидалась ошибка запроса в транзакции")
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback вернул ошибку: %v", err)
+	}
+	if _, err := sqlDB.Exec("SELECT 1"); err != nil {
+		t.Errorf("После отката транзакции запросы должны работать: %v", err)
+	}
+
+	expected := []string{
+		"SAVEPOINT dbtest_stmt", "fail", "ROLLBACK TO SAVEPOINT dbtest_stmt",
+		"SAVEPOINT dbtest_stmt", "SELECT name FROM users", "RELEASE SAVEPOINT dbtest_stmt",
+		"SAVEPOINT dbtest_1", "fail", "ROLLBACK TO SAVEPOINT dbtest_1",
+		"SAVEPOINT dbtest_stmt", "SELECT 1", "RELEASE SAVEPOINT dbtest_stmt",
+	}
+	if !reflect.DeepEqual(raw.queries, expected) {
+		t.Errorf("Ожидались запросы %v, а получили %v", expected, raw.queries)
+	}
+}
+
+func TestBufferedRowsCopiesBytes(t *testing.T) {
+	buf := []byte("first")
+	rows, err := readRows(&reusingRows{buf: buf, left: 2})
+	if err != nil {
+		t.Fatalf("readRows вернула ошибку: %v", err)
+	}
+	dest := make([]driver.Value, 1)
+	_ = rows.Next(dest)
+	if string(dest[0].([]byte)) != "first" {
+		t.Errorf("Строки не должны портиться при переиспользовании буфера драйвером, а получили %s", dest[0])
+	}
+	if err := rows.Next(dest); err != nil {
+		t.Fatalf("Ожидалась вторая строка: %v", err)
+	}
+	if err := rows.Next(dest); err != io.EOF {
+		t.Errorf("Ожидался io.EOF, а получили %v", err)
+	}
+}
+
+// reusingRows отдаёт один и тот же буфер, перезаписывая его, как делают драйверы
+type reusingRows struct {
+	buf  []byte
+	left int
+}
+
+func (r *reusingRows) Columns() []string { return []string{"v"} }
+func (r *reusingRows) Close() error      { return nil }
+
+func (r *reusingRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	if r.left == 1 {
+		copy(r.buf, "secnd")
+	}
+	r.left--
+	dest[0] = r.buf
+	return nil
+}
+
+type note struct {
+	ID   int64  `db:"id"`
+	Text string `db:"text"`
+}
+
+func TestNew(t *testing.T) {
+	migrations := fstest.MapFS{
+		"0001_create_notes.up.sql": {Data: []byte("CREATE TABLE notes (id BIGSERIAL PRIMARY KEY, text TEXT NOT NULL);")},
+	}
+	conn := New(t, Config{Migrations: migrations})
+	repo := db.NewBaseRepository[note](conn, db.WithTable("notes"))
+	ctx := context.Background()
+
+	n := &note{Text: "hello"}
+	if err := repo.Create(ctx, n); err != nil {
+		t.Fatalf("Create вернул ошибку: %v", err)
+	}
+	err := repo.WithTrx(ctx, func(tx *sqlx.Tx) error {
+		n.Text = "updated"
+		return repo.UpdateTx(ctx, tx, n)
+	})
+	if err != nil {
+		t.Fatalf("Transaction вернула ошибку: %v", err)
+	}
+
+	got, err := repo.Get(ctx, n.ID)
+	if err != nil || got.Text != "updated" {
+		t.Errorf("Ожидалась обновлённая запись, а получили %+v, %v", got, err)
+	}
+}
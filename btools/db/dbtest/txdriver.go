@@ -0,0 +1,287 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// errUnsupportedDriver - драйвер не умеет выполнять запросы без подготовки (lib/pq и pgx умеют).
+var errUnsupportedDriver = errors.New("dbtest: driver must implement ExecerContext and QueryerContext")
+
+// session - одно физическое соединение с открытой транзакцией, которая откатывается в конце теста.
+// Все соединения пула *sql.DB работают через него по очереди, а BEGIN/COMMIT/ROLLBACK
+// превращаются в SAVEPOINT/RELEASE/ROLLBACK TO, поэтому тестируемый код может сам открывать транзакции.
+type session struct {
+	mu        sync.Mutex
+	conn      driver.Conn
+	tx        driver.Tx
+	savepoint int
+	open      int // savepoint-транзакции тестируемого кода, которые ещё не завершены
+	closed    bool
+}
+
+// statementSavepoint - savepoint вокруг запроса вне транзакции тестируемого кода.
+const statementSavepoint = "dbtest_stmt"
+
+func newSession(ctx context.Context, conn driver.Conn) (*session, error) {
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if c, ok := conn.(driver.ConnBeginTx); ok {
+		tx, err = c.BeginTx(ctx, driver.TxOptions{})
+	} else {
+		tx, err = conn.Begin()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &session{conn: conn, tx: tx}, nil
+}
+
+// rollback откатывает всё, что сделал тест, и закрывает соединение.
+func (s *session) rollback() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.tx.Rollback()
+	return errors.Join(err, s.conn.Close())
+}
+
+func (s *session) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result driver.Result
+	err := s.statement(ctx, func() error {
+		var err error
+		result, err = s.execLocked(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+// query читает результат целиком: соединение общее, и открытый курсор
+// помешал бы следующему запросу из другого соединения пула.
+func (s *session) query(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result driver.Rows
+	err := s.statement(ctx, func() error {
+		if s.closed {
+			return driver.ErrBadConn
+		}
+		queryer, ok := s.conn.(driver.QueryerContext)
+		if !ok {
+			return errUnsupportedDriver
+		}
+		rows, err := queryer.QueryContext(ctx, query, args)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		result, err = readRows(rows)
+		return err
+	})
+	return result, err
+}
+
+// statement выполняет fn в собственном savepoint, если тестируемый код не открыл транзакцию:
+// иначе одна ошибка (например, нарушение уникальности, которое проверяет тест) перевела бы
+// всю транзакцию теста в состояние "current transaction is aborted".
+func (s *session) statement(ctx context.Context, fn func() error) error {
+	if s.open > 0 {
+		return fn()
+	}
+	if _, err := s.execLocked(ctx, "SAVEPOINT "+statementSavepoint, nil); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		_, _ = s.execLocked(context.Background(), "ROLLBACK TO SAVEPOINT "+statementSavepoint, nil)
+		return err
+	}
+	_, err := s.execLocked(context.Background(), "RELEASE SAVEPOINT "+statementSavepoint, nil)
+	return err
+}
+
+func (s *session) execLocked(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if s.closed {
+		return nil, driver.ErrBadConn
+	}
+	execer, ok := s.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, errUnsupportedDriver
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (s *session) begin(ctx context.Context) (driver.Tx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.savepoint++
+	name := fmt.Sprintf("dbtest_%d", s.savepoint)
+	if _, err := s.execLocked(ctx, "SAVEPOINT "+name, nil); err != nil {
+		return nil, err
+	}
+	s.open++
+	return &savepointTx{session: s, name: name}, nil
+}
+
+// finish завершает savepoint-транзакцию тестируемого кода.
+func (s *session) finish(query string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open--
+	_, err := s.execLocked(context.Background(), query, nil)
+	return err
+}
+
+type savepointTx struct {
+	session *session
+	name    string
+}
+
+func (tx *savepointTx) Commit() error {
+	return tx.session.finish("RELEASE SAVEPOINT " + tx.name)
+}
+
+func (tx *savepointTx) Rollback() error {
+	return tx.session.finish("ROLLBACK TO SAVEPOINT " + tx.name)
+}
+
+// connector отдаёт database/sql соединения, разделяющие одну session.
+type connector struct {
+	session *session
+	driver  driver.Driver
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{session: c.session}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+type conn struct {
+	session *session
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{session: c.session, query: query}, nil
+}
+
+// Close не трогает session: её закрывает только откат в конце теста.
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.session.begin(context.Background())
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, errors.New("dbtest: isolation level and read-only transactions are not supported inside a test transaction")
+	}
+	return c.session.begin(ctx)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.session.exec(ctx, query, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.session.query(ctx, query, args)
+}
+
+// CheckNamedValue отдаёт проверку аргументов настоящему драйверу, если он это умеет.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.session.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	session *session
+	query   string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.session.exec(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.session.query(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.session.exec(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.session.query(ctx, s.query, args)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		result[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return result
+}
+
+// bufferedRows - результат запроса, уже прочитанный в память.
+type bufferedRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func readRows(rows driver.Rows) (*bufferedRows, error) {
+	result := &bufferedRows{columns: rows.Columns()}
+	for {
+		dest := make([]driver.Value, len(result.columns))
+		if err := rows.Next(dest); err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return nil, err
+		}
+		// драйверы могут переиспользовать буфер []byte между строками
+		for i, v := range dest {
+			if b, ok := v.([]byte); ok {
+				dest[i] = append([]byte(nil), b...)
+			}
+		}
+		result.rows = append(result.rows, dest)
+	}
+}
+
+func (r *bufferedRows) Columns() []string {
+	return r.columns
+}
+
+func (r *bufferedRows) Close() error {
+	return nil
+}
+
+func (r *bufferedRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
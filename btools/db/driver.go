@@ -0,0 +1,62 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// Driver - драйвер database/sql, через который открывается подключение.
+type Driver string
+
+const (
+	DriverPQ  Driver = "postgres" // github.com/lib/pq
+	DriverPgx Driver = "pgx"      // github.com/jackc/pgx/v5/stdlib
+)
+
+func (c *Config) driverName() string {
+	if c.Driver == "" {
+		return string(DriverPQ)
+	}
+	return string(c.Driver)
+}
+
+// pgError - общие поля ошибок lib/pq и pgx.
+type pgError struct {
+	Code       string
+	Message    string
+	Detail     string
+	Table      string
+	Column     string
+	Constraint string
+}
+
+func asPgError(err error) (*pgError, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &pgError{
+			Code:       string(pqErr.Code),
+			Message:    pqErr.Message,
+			Detail:     pqErr.Detail,
+			Table:      pqErr.Table,
+			Column:     pqErr.Column,
+			Constraint: pqErr.Constraint,
+		}, true
+	}
+
+	var pgxErr *pgconn.PgError
+	if errors.As(err, &pgxErr) {
+		return &pgError{
+			Code:       pgxErr.Code,
+			Message:    pgxErr.Message,
+			Detail:     pgxErr.Detail,
+			Table:      pgxErr.TableName,
+			Column:     pgxErr.ColumnName,
+			Constraint: pgxErr.ConstraintName,
+		}, true
+	}
+
+	return nil, false
+}
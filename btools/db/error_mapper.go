@@ -1,40 +1,268 @@
 package db
 
 import (
+	"database/sql"
+	"embed"
 	"errors"
-	"github.com/lib/pq"
+	"sync"
+
 	"github.com/seemyown/backend-toolkit/btools/exc"
 )
 
-func MapPGError(err error) error {
-	var pgErr *pq.Error
-	if errors.As(err, &pgErr) {
-		switch pgErr.Code {
-		case "23503":
-			return exc.NotFoundError("not_found", "Ресурс не найден")
-		case "23505":
-			return exc.ConflictError("already_exists", "Запись уже существует")
-		case "23502":
-			// not_null_violation
-			return exc.ValidationError("not_null_violation", "", "Обязательное поле не может быть пустым")
-		case "23514":
-			// check_violation
-			return exc.ValidationError("check_violation", "", "Нарушение ограничения")
-		case "22001":
-			// string_data_right_truncation
-			return exc.ValidationError("string_too_long", "", "Строка слишком длинная")
-		case "22P02":
-			// invalid_text_representation
-			return exc.ValidationError("invalid_format", "", "Неверный формат данных")
-		case "22007":
-			// invalid_datetime_format
-			return exc.ValidationError("invalid_date_format", "", "Неверный формат даты")
-		case "42P02":
-			// undefined_parameter
-			return exc.BadRequestError("undefined_parameter", "Передан неизвестный параметр")
-		default:
-			return exc.RepositoryError(err.Error())
+// ErrorMapping описывает, во что превращается ошибка Postgres: внутренний код, строковый
+// код для клиента, HTTP-статус, поле и локализованные сообщения.
+// Без Messages сообщение берётся из exc.DefaultCatalog по Reason.
+type ErrorMapping struct {
+	Code     int               // внутренний код RepositoryError
+	Reason   string            // строковый код ошибки для клиента, например "already_exists"
+	Status   int               // HTTP-статус; если 0 - берётся из MapToHttpError по Code
+	Field    string            // поле, к которому относится ошибка
+	Messages map[string]string // сообщения по языкам
+	// Description - описание кода для документации (см. exc.Registry)
+	Description string
+}
+
+// merge накладывает непустые поля other поверх m.
+func (m ErrorMapping) merge(other ErrorMapping) ErrorMapping {
+	if other.Code != 0 {
+		m.Code = other.Code
+	}
+	if other.Reason != "" {
+		m.Reason = other.Reason
+	}
+	if other.Status != 0 {
+		m.Status = other.Status
+	}
+	if other.Field != "" {
+		m.Field = other.Field
+	}
+	if len(other.Messages) > 0 {
+		m.Messages = other.Messages
+	}
+	if other.Description != "" {
+		m.Description = other.Description
+	}
+	return m
+}
+
+// codeDefinition возвращает объявление Reason для exc.Registry. ok = false, если
+// статус нельзя определить (отображение ограничения без Code и Status).
+func (m ErrorMapping) codeDefinition() (exc.CodeDefinition, bool) {
+	status := m.Status
+	if status == 0 {
+		status = MapToHttpError[m.Code]
+	}
+	if m.Reason == "" || status == 0 {
+		return exc.CodeDefinition{}, false
+	}
+	return exc.CodeDefinition{Code: m.Reason, Status: status, Description: m.Description, Messages: m.Messages}, true
+}
+
+// declare объявляет Reason в exc.DefaultRegistry, если такой код ещё не объявлен:
+// одинаковый Reason у нескольких ограничений - нормальная ситуация.
+func declare(mapping ErrorMapping) {
+	def, ok := mapping.codeDefinition()
+	if !ok {
+		return
+	}
+	if _, exists := exc.DefaultRegistry.Lookup(def.Code); !exists {
+		_ = exc.Register(def)
+	}
+}
+
+type errorRegistry struct {
+	mu          sync.RWMutex
+	states      map[string]ErrorMapping
+	constraints map[string]ErrorMapping
+}
+
+var registry = &errorRegistry{
+	states: map[string]ErrorMapping{
+		"23505": {Code: ErrCodeUniqueViolation, Reason: "already_exists",
+			Description: "A record with the same unique key already exists"},
+		"23503": {Code: ErrCodeForeignKeyViolation, Reason: "foreign_key_violation",
+			Description: "A referenced record does not exist"},
+		"23502": {Code: ErrCodeNotNullViolation, Reason: "not_null_violation",
+			Description: "A required field is empty"},
+		"23514": {Code: ErrCodeCheckViolation, Reason: "check_violation",
+			Description: "A value violates a CHECK constraint"},
+		"23P01": {Code: ErrCodeExclusionViolation, Reason: "exclusion_violation",
+			Description: "A value conflicts with an existing record (EXCLUDE constraint)"},
+		"23001": {Code: ErrCodeRestrictViolation, Reason: "restrict_violation",
+			Description: "The record is still referenced and cannot be deleted"},
+		"40P01": {Code: ErrCodeDeadlockDetected, Reason: "deadlock_detected",
+			Description: "The transaction was aborted due to a deadlock, retry the request"},
+		"40001": {Code: ErrCodeSerializationFailure, Reason: "serialization_failure",
+			Description: "The transaction could not be serialized, retry the request"},
+		"22001": {Code: ErrCodeStringTooLong, Reason: "string_too_long",
+			Description: "A string value is longer than the column allows"},
+		"22003": {Code: ErrCodeNumericOutOfRange, Reason: "numeric_out_of_range",
+			Description: "A numeric value is out of range"},
+		"22P02": {Code: ErrCodeInvalidTextRepresentation, Reason: "invalid_format",
+			Description: "A value has an invalid format"},
+		"22007": {Code: ErrCodeInvalidDatetimeFormat, Reason: "invalid_date_format",
+			Description: "A date or time value has an invalid format"},
+		"42P02": {Code: ErrCodeUndefinedParameter, Reason: "undefined_parameter",
+			Description: "The query references an unknown parameter"},
+	},
+	constraints: map[string]ErrorMapping{},
+}
+
+var (
+	notFoundMapping               = ErrorMapping{Code: ErrCodeNotFound, Reason: "not_found"}
+	concurrentModificationMapping = ErrorMapping{Code: ErrCodeConcurrentModification, Reason: "concurrent_modification",
+		Description: "The record was changed by another request, reload it and retry"}
+	unhandledMapping = ErrorMapping{Code: ErrCodeUnhandled, Reason: "repository_error",
+		Description: "Unexpected database error"}
+)
+
+// locales - сообщения для Reason встроенных отображений, загружаются в exc.DefaultCatalog.
+//
+//go:embed locales
+var locales embed.FS
+
+func init() {
+	if err := exc.DefaultCatalog.Load(locales, "locales"); err != nil {
+		panic(err)
+	}
+	// not_found объявлен в exc
+	defs := []exc.CodeDefinition{}
+	for _, mapping := range []ErrorMapping{concurrentModificationMapping, unhandledMapping} {
+		def, _ := mapping.codeDefinition()
+		defs = append(defs, def)
+	}
+	for _, mapping := range registry.states {
+		def, _ := mapping.codeDefinition()
+		defs = append(defs, def)
+	}
+	exc.MustRegister(defs...)
+}
+
+// defaultReason - строковый код встроенного отображения для внутреннего кода.
+func defaultReason(code int) string {
+	for _, mapping := range []ErrorMapping{notFoundMapping, concurrentModificationMapping, unhandledMapping} {
+		if mapping.Code == code {
+			return mapping.Reason
+		}
+	}
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	for _, mapping := range registry.states {
+		if mapping.Code == code {
+			return mapping.Reason
+		}
+	}
+	return ""
+}
+
+// RegisterSQLState задаёт (или переопределяет) отображение для кода SQLSTATE, например "23505".
+func RegisterSQLState(state string, mapping ErrorMapping) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.states[state] = mapping
+	declare(mapping)
+}
+
+// RegisterConstraint задаёт отображение для конкретного ограничения, например "users_email_key".
+// Незаполненные поля берутся из отображения SQLSTATE ошибки.
+func RegisterConstraint(constraint string, mapping ErrorMapping) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.constraints[constraint] = mapping
+	declare(mapping)
+}
+
+func (r *errorRegistry) lookup(state, constraint string) ErrorMapping {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mapping, ok := r.states[state]
+	if !ok {
+		mapping = unhandledMapping
+	}
+	if constraint != "" {
+		if byConstraint, ok := r.constraints[constraint]; ok {
+			mapping = mapping.merge(byConstraint)
 		}
 	}
-	return exc.RepositoryError(err.Error())
+	return mapping
+}
+
+// WrapError превращает ошибку драйвера (lib/pq или pgx) в RepositoryError по реестру отображений.
+func WrapError(err error) *RepositoryError {
+	if err == nil {
+		return nil
+	}
+
+	var repoErr *RepositoryError
+	if errors.As(err, &repoErr) {
+		return repoErr
+	}
+
+	mapping := unhandledMapping
+	if errors.Is(err, sql.ErrNoRows) {
+		mapping = notFoundMapping
+	}
+
+	pgErr, ok := asPgError(err)
+	if !ok {
+		return newRepositoryError(mapping, err)
+	}
+
+	mapping = registry.lookup(pgErr.Code, pgErr.Constraint)
+	repoErr = newRepositoryError(mapping, err)
+	repoErr.SQLState = pgErr.Code
+	repoErr.Constraint = pgErr.Constraint
+	repoErr.Table = pgErr.Table
+	repoErr.Column = pgErr.Column
+	repoErr.Detail = pgErr.Detail
+	if repoErr.Field == "" {
+		repoErr.Field = pgErr.Column
+	}
+	if repoErr.Field == "" {
+		repoErr.Field = fieldFromConstraint(pgErr.Table, pgErr.Constraint)
+	}
+	return repoErr
+}
+
+// MapPGError возвращает ошибку в виде exc.Error с прежними кодами и статусами
+// (например, 23503 - 404 not_found, 23502 и 23514 - 422), чтобы не менять ответы
+// сервисов, которые ещё не перешли на WrapError. Новые коды из реестра сюда не попадают.
+//
+// Deprecated: используйте WrapError; RepositoryError обрабатывается middleware.ErrorMiddleware
+// и приводится к exc.Error через RepositoryError.AppError.
+func MapPGError(err error) error {
+	if err == nil {
+		return nil
+	}
+	pgErr, ok := asPgError(err)
+	if !ok {
+		return exc.RepositoryError(err.Error())
+	}
+	switch pgErr.Code {
+	case "23503":
+		return exc.NotFoundError("not_found", "Ресурс не найден")
+	case "23505":
+		return exc.ConflictError("already_exists", "Запись уже существует")
+	case "23502":
+		// not_null_violation
+		return exc.ValidationError("not_null_violation", "", "Обязательное поле не может быть пустым")
+	case "23514":
+		// check_violation
+		return exc.ValidationError("check_violation", "", "Нарушение ограничения")
+	case "22001":
+		// string_data_right_truncation
+		return exc.ValidationError("string_too_long", "", "Строка слишком длинная")
+	case "22P02":
+		// invalid_text_representation
+		return exc.ValidationError("invalid_format", "", "Неверный формат данных")
+	case "22007":
+		// invalid_datetime_format
+		return exc.ValidationError("invalid_date_format", "", "Неверный формат даты")
+	case "42P02":
+		// undefined_parameter
+		return exc.BadRequestError("undefined_parameter", "Передан неизвестный параметр")
+	default:
+		return exc.RepositoryError(err.Error())
+	}
 }
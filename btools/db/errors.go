@@ -4,16 +4,68 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"strings"
 
-	"github.com/lib/pq"
+	"github.com/seemyown/backend-toolkit/btools/exc"
 )
 
 // RepositoryError представляет ошибку уровня хранилища (репозитория).
 // Она содержит собственный код, сообщение для пользователя и оригинальную ошибку.
 type RepositoryError struct {
 	Code              int               // внутренний код ошибки (не SQLSTATE)
+	Reason            string            // строковый код ошибки для клиента, например "already_exists"
+	Status            int               // HTTP-статус; если 0 - берётся из MapToHttpError
+	Field             string            // поле, к которому относится ошибка, если известно
 	LocalizedMessages map[string]string // понятное сообщение об ошибке
 	Err               error             // исходная ошибка, возвращённая драйвером или другим уровнем
+
+	// Данные из ошибки Postgres. Detail может содержать значения из запроса,
+	// поэтому наружу его отдавать нельзя.
+	SQLState   string
+	Constraint string
+	Table      string
+	Column     string
+	Detail     string
+}
+
+func newRepositoryError(mapping ErrorMapping, err error) *RepositoryError {
+	return &RepositoryError{
+		Code:              mapping.Code,
+		Reason:            mapping.Reason,
+		Status:            mapping.Status,
+		Field:             mapping.Field,
+		LocalizedMessages: mapping.Messages,
+		Err:               err,
+	}
+}
+
+var constraintSuffixes = []string{"_key", "_fkey", "_pkey", "_check", "_excl", "_not_null", "_idx"}
+
+// fieldFromConstraint угадывает поле по имени ограничения в стиле Postgres:
+// users_email_key -> email, orders_user_id_fkey -> user_id.
+func fieldFromConstraint(table, constraint string) string {
+	if table == "" || !strings.HasPrefix(constraint, table+"_") {
+		return ""
+	}
+	name := strings.TrimPrefix(constraint, table+"_")
+	for _, suffix := range constraintSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return ""
+}
+
+// IsValidation сообщает, что ошибка вызвана данными клиента (нарушение ограничений,
+// неверный формат), а не сбоем базы. Для таких ошибок имеет смысл показывать поле.
+func (e *RepositoryError) IsValidation() bool {
+	switch e.Code {
+	case ErrCodeUniqueViolation, ErrCodeForeignKeyViolation, ErrCodeNotNullViolation,
+		ErrCodeCheckViolation, ErrCodeExclusionViolation, ErrCodeStringTooLong,
+		ErrCodeNumericOutOfRange, ErrCodeInvalidTextRepresentation, ErrCodeInvalidDatetimeFormat:
+		return true
+	}
+	return false
 }
 
 // Error удовлетворяет интерфейсу error, возвращая сообщение об ошибке.
@@ -21,11 +73,50 @@ func (e *RepositoryError) Error() string {
 	return e.MessageFor("en")
 }
 
+// MessageFor возвращает сообщение на языке lang: из Messages отображения, затем из
+// exc.DefaultCatalog по Reason, а если Reason переопределён без перевода - по стандартному
+// коду для Code.
 func (e *RepositoryError) MessageFor(lang string) string {
+	catalog := exc.DefaultCatalog
 	if msg, ok := e.LocalizedMessages[lang]; ok {
 		return msg
 	}
-	return e.LocalizedMessages["ru"] // fallback
+	if msg, ok := catalog.Lookup(lang, e.Reason); ok {
+		return msg
+	}
+	if msg, ok := e.LocalizedMessages[catalog.Fallback()]; ok {
+		return msg
+	}
+	for _, reason := range []string{e.Reason, defaultReason(e.Code), unhandledMapping.Reason} {
+		if msg, ok := catalog.Message(lang, reason); ok {
+			return msg
+		}
+	}
+	return ""
+}
+
+// HTTPStatus возвращает HTTP-статус, соответствующий ошибке.
+func (e *RepositoryError) HTTPStatus() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	if status, ok := MapToHttpError[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// AppError приводит ошибку к exc.Error с сообщением на языке lang.
+func (e *RepositoryError) AppError(lang string) *exc.Error {
+	status := e.HTTPStatus()
+	return exc.NewAppError(exc.ErrorName(status), e.reason(), e.MessageFor(lang), status).WithCause(e)
+}
+
+func (e *RepositoryError) reason() string {
+	if e.Reason == "" {
+		return unhandledMapping.Reason
+	}
+	return e.Reason
 }
 
 // Unwrap позволяет извлечь вложенную оригинальную ошибку (для errors.Is/As).
@@ -33,6 +124,24 @@ func (e *RepositoryError) Unwrap() error {
 	return e.Err
 }
 
+// Is сравнивает ошибку с exc.Error так же, как AppError (errors.Is(err, exc.ErrNotFound)),
+// а ошибку с кодом ErrCodeNotFound - ещё и с sql.ErrNoRows, даже если исходная ошибка другая.
+func (e *RepositoryError) Is(target error) bool {
+	if target == sql.ErrNoRows {
+		return e.Code == ErrCodeNotFound
+	}
+	if _, ok := target.(*exc.Error); ok {
+		return (&exc.Error{Code: e.reason(), StatusCode: e.HTTPStatus()}).Is(target)
+	}
+	return false
+}
+
+// IsNotFound сообщает, что запись не найдена: sql.ErrNoRows, RepositoryError с ErrCodeNotFound
+// или любая ошибка вида exc.ErrNotFound.
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, exc.ErrNotFound)
+}
+
 // Набор констант с внутренними кодами ошибок для RepositoryError.
 const (
 	ErrCodeNotFound                  = 10  // Например, запись не найдена (sql.ErrNoRows)
@@ -45,8 +154,11 @@ const (
 	ErrCodeStringTooLong             = 21  // Превышение допустимой длины строки
 	ErrCodeNumericOutOfRange         = 22  // Числовое значение вне допустимого диапазона
 	ErrCodeInvalidTextRepresentation = 23  // Неверный формат входных данных
+	ErrCodeInvalidDatetimeFormat     = 24  // Неверный формат даты/времени
+	ErrCodeUndefinedParameter        = 25  // Передан неизвестный параметр запроса
 	ErrCodeDeadlockDetected          = 31  // Обнаружен дедлок
 	ErrCodeSerializationFailure      = 32  // Ошибка сериализации транзакции
+	ErrCodeConcurrentModification    = 33  // Запись изменена параллельно (оптимистическая блокировка)
 	ErrCodeUnhandled                 = 999 // Неизвестная/необработанная ошибка
 )
 
@@ -61,82 +173,10 @@ var MapToHttpError = map[int]int{
 	ErrCodeStringTooLong:             http.StatusRequestEntityTooLarge,
 	ErrCodeNumericOutOfRange:         http.StatusRequestedRangeNotSatisfiable,
 	ErrCodeInvalidTextRepresentation: http.StatusUnprocessableEntity,
+	ErrCodeInvalidDatetimeFormat:     http.StatusUnprocessableEntity,
+	ErrCodeUndefinedParameter:        http.StatusBadRequest,
 	ErrCodeDeadlockDetected:          http.StatusGatewayTimeout,
 	ErrCodeSerializationFailure:      http.StatusInternalServerError,
+	ErrCodeConcurrentModification:    http.StatusConflict,
 	ErrCodeUnhandled:                 http.StatusInternalServerError,
 }
-
-func localized(messageRu, messageEn string) map[string]string {
-	return map[string]string{
-		"ru": messageRu,
-		"en": messageEn,
-	}
-}
-
-func WrapError(err error) *RepositoryError {
-	if err == nil {
-		return nil
-	}
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return &RepositoryError{
-			Code:              ErrCodeNotFound,
-			LocalizedMessages: localized("Запись не найдена", "Record not found"),
-			Err:               err,
-		}
-	}
-
-	var pqErr *pq.Error
-	if errors.As(err, &pqErr) {
-		code := ErrCodeUnhandled
-		messages := localized("Необработанная ошибка", "Unhandled database error")
-
-		switch pqErr.Code.Name() {
-		case "unique_violation":
-			code = ErrCodeUniqueViolation
-			messages = localized("Нарушение уникального ограничения", "Unique constraint violated")
-		case "foreign_key_violation":
-			code = ErrCodeForeignKeyViolation
-			messages = localized("Нарушение внешнего ключа", "Foreign key constraint violated")
-		case "not_null_violation":
-			code = ErrCodeNotNullViolation
-			messages = localized("NULL в поле, где он запрещён", "NULL value where NOT NULL is required")
-		case "check_violation":
-			code = ErrCodeCheckViolation
-			messages = localized("Нарушение CHECK ограничения", "CHECK constraint violated")
-		case "exclusion_violation":
-			code = ErrCodeExclusionViolation
-			messages = localized("Нарушение EXCLUDE ограничения", "Exclusion constraint violated")
-		case "restrict_violation":
-			code = ErrCodeRestrictViolation
-			messages = localized("Удаление запрещено (RESTRICT)", "Delete restricted due to FK")
-		case "deadlock_detected":
-			code = ErrCodeDeadlockDetected
-			messages = localized("Обнаружен дедлок", "Deadlock detected")
-		case "serialization_failure":
-			code = ErrCodeSerializationFailure
-			messages = localized("Ошибка сериализации транзакции", "Transaction serialization failure")
-		case "string_data_right_truncation":
-			code = ErrCodeStringTooLong
-			messages = localized("Строка слишком длинная", "String too long for field")
-		case "numeric_value_out_of_range":
-			code = ErrCodeNumericOutOfRange
-			messages = localized("Число вне допустимого диапазона", "Numeric value out of range")
-		case "invalid_text_representation":
-			code = ErrCodeInvalidTextRepresentation
-			messages = localized("Неверный формат входных данных", "Invalid input format")
-		}
-
-		return &RepositoryError{
-			Code:              code,
-			LocalizedMessages: messages,
-			Err:               err,
-		}
-	}
-
-	return &RepositoryError{
-		Code:              ErrCodeUnhandled,
-		LocalizedMessages: localized("Необработанная ошибка", "Unhandled database error"),
-		Err:               err,
-	}
-}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/seemyown/backend-toolkit/btools/exc"
+)
+
+func TestWrapError_SQLState(t *testing.T) {
+	err := WrapError(fmt.Errorf("insert user: %w", &pq.Error{Code: "23505", Constraint: "users_login_key"}))
+	if err.Code != ErrCodeUniqueViolation || err.Reason != "already_exists" {
+		t.Errorf("Ожидалось нарушение уникальности, а получили %+v", err)
+	}
+	if err.HTTPStatus() != http.StatusConflict {
+		t.Errorf("Ожидался статус 409, а получили %d", err.HTTPStatus())
+	}
+	if err.MessageFor("en") != "Record already exists" {
+		t.Errorf("Неверное сообщение: %s", err.MessageFor("en"))
+	}
+}
+
+func TestWrapError_Constraint(t *testing.T) {
+	RegisterConstraint("test_users_email_key", ErrorMapping{
+		Reason:   "email_taken",
+		Field:    "email",
+		Status:   http.StatusUnprocessableEntity,
+		Messages: map[string]string{"ru": "Email уже занят", "en": "Email is already taken"},
+	})
+
+	err := WrapError(&pq.Error{Code: "23505", Constraint: "test_users_email_key"})
+	if err.Code != ErrCodeUniqueViolation {
+		t.Errorf("Код должен браться из отображения SQLSTATE, а получили %d", err.Code)
+	}
+	if err.Reason != "email_taken" || err.Field != "email" || err.HTTPStatus() != http.StatusUnprocessableEntity {
+		t.Errorf("Отображение ограничения не применилось: %+v", err)
+	}
+	if err.MessageFor("ru") != "Email уже занят" {
+		t.Errorf("Неверное сообщение: %s", err.MessageFor("ru"))
+	}
+}
+
+func TestWrapError_NotFoundAndUnhandled(t *testing.T) {
+	notFound := WrapError(sql.ErrNoRows)
+	if notFound.Code != ErrCodeNotFound || notFound.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("Ожидалась ошибка not found, а получили %+v", notFound)
+	}
+	if !errors.Is(notFound, sql.ErrNoRows) {
+		t.Errorf("RepositoryError должен оборачивать исходную ошибку")
+	}
+
+	unhandled := WrapError(errors.New("connection reset"))
+	if unhandled.Code != ErrCodeUnhandled || unhandled.HTTPStatus() != http.StatusInternalServerError {
+		t.Errorf("Ожидалась необработанная ошибка, а получили %+v", unhandled)
+	}
+	if WrapError(unhandled) != unhandled {
+		t.Errorf("Повторное оборачивание должно возвращать ту же ошибку")
+	}
+}
+
+func TestMapPGError(t *testing.T) {
+	// устаревшая MapPGError сохраняет прежние коды и статусы
+	cases := []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{&pq.Error{Code: "23503"}, "not_found", http.StatusNotFound},
+		{&pq.Error{Code: "23505"}, "already_exists", http.StatusConflict},
+		{&pq.Error{Code: "23502"}, "not_null_violation", http.StatusUnprocessableEntity},
+		{&pq.Error{Code: "23514"}, "check_violation", http.StatusUnprocessableEntity},
+		{&pq.Error{Code: "22P02"}, "invalid_format", http.StatusUnprocessableEntity},
+		{&pq.Error{Code: "42P02"}, "undefined_parameter", http.StatusBadRequest},
+		{&pq.Error{Code: "40001"}, "internal_server_error", http.StatusInternalServerError},
+		{errors.New("connection refused"), "internal_server_error", http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		var appErr *exc.Error
+		if !errors.As(MapPGError(c.err), &appErr) {
+			t.Fatalf("MapPGError должна возвращать *exc.Error")
+		}
+		if appErr.Code != c.code || appErr.StatusCode != c.status {
+			t.Errorf("Для %v ожидалось %s/%d, а получили %s/%d", c.err, c.code, c.status, appErr.Code, appErr.StatusCode)
+		}
+	}
+
+	var appErr *exc.Error
+	errors.As(MapPGError(errors.New("connection refused")), &appErr)
+	if appErr.Message != "repository_error: [connection refused]" {
+		t.Errorf("Для ошибок не из Postgres ожидалось прежнее сообщение, а получили %s", appErr.Message)
+	}
+	if MapPGError(nil) != nil {
+		t.Errorf("Для nil ожидался nil")
+	}
+}
+
+func TestWrapError_ConstraintDetails(t *testing.T) {
+	err := WrapError(&pq.Error{
+		Code:       "23505",
+		Table:      "users",
+		Constraint: "users_email_key",
+		Detail:     "Key (email)=(a@b.c) already exists.",
+	})
+	if err.SQLState != "23505" || err.Table != "users" || err.Constraint != "users_email_key" || err.Detail == "" {
+		t.Errorf("Данные ошибки Postgres не сохранены: %+v", err)
+	}
+	if err.Field != "email" {
+		t.Errorf("Ожидалось поле email из имени ограничения, а получили %q", err.Field)
+	}
+	if !err.IsValidation() {
+		t.Errorf("Нарушение уникальности должно считаться ошибкой валидации")
+	}
+
+	fk := WrapError(&pq.Error{Code: "23503", Table: "orders", Constraint: "orders_user_id_fkey"})
+	if fk.Field != "user_id" {
+		t.Errorf("Ожидалось поле user_id, а получили %q", fk.Field)
+	}
+
+	notNull := WrapError(&pq.Error{Code: "23502", Table: "users", Column: "name"})
+	if notNull.Field != "name" {
+		t.Errorf("Ожидалось поле из колонки, а получили %q", notNull.Field)
+	}
+
+	if WrapError(&pq.Error{Code: "40P01"}).IsValidation() {
+		t.Errorf("Дедлок не является ошибкой валидации")
+	}
+}
+
+func TestWrapError_Pgx(t *testing.T) {
+	err := WrapError(fmt.Errorf("insert order: %w", &pgconn.PgError{
+		Code:           "23503",
+		TableName:      "orders",
+		ConstraintName: "orders_user_id_fkey",
+	}))
+	if err.Code != ErrCodeForeignKeyViolation || err.SQLState != "23503" {
+		t.Errorf("Ошибка pgx должна отображаться так же, как lib/pq: %+v", err)
+	}
+	if err.Field != "user_id" {
+		t.Errorf("Ожидалось поле user_id, а получили %q", err.Field)
+	}
+	if sqlState(err) != "23503" {
+		t.Errorf("sqlState должна понимать ошибки pgx")
+	}
+}
+
+func TestRepositoryError_Is(t *testing.T) {
+	notFound := fmt.Errorf("get user: %w", &RepositoryError{Code: ErrCodeNotFound, Reason: "not_found", Err: errors.New("no rows in result set")})
+	if !errors.Is(notFound, exc.ErrNotFound) || !errors.Is(notFound, sql.ErrNoRows) {
+		t.Errorf("Ошибка с ErrCodeNotFound должна совпадать с exc.ErrNotFound и sql.ErrNoRows")
+	}
+	if !IsNotFound(notFound) || !IsNotFound(sql.ErrNoRows) || !IsNotFound(exc.NotFoundError("user_not_found", "")) {
+		t.Errorf("IsNotFound должна узнавать все виды not found")
+	}
+
+	duplicate := WrapError(&pq.Error{Code: "23505"})
+	if !errors.Is(duplicate, exc.ErrConflict) || errors.Is(duplicate, exc.ErrNotFound) || IsNotFound(duplicate) {
+		t.Errorf("Нарушение уникальности должно совпадать только с exc.ErrConflict")
+	}
+	if !errors.Is(duplicate, exc.ConflictError("already_exists", "")) {
+		t.Errorf("RepositoryError должен совпадать с exc.Error с тем же кодом")
+	}
+	if !errors.Is(WrapError(&pq.Error{Code: "23502"}), exc.ErrValidation) {
+		t.Errorf("Нарушение NOT NULL должно совпадать с exc.ErrValidation")
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+)
+
+var inListRe = regexp.MustCompile(`\bin ?\( ?\?(?: ?, ?\?)* ?\)`)
+
+// Fingerprint нормализует запрос для группировки в логах и метриках: убирает комментарии,
+// заменяет литералы и плейсхолдеры на ?, сворачивает списки IN (?, ?, ...) и пробелы.
+func Fingerprint(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	src := []rune(query)
+	n := len(src)
+	space := false
+	for i := 0; i < n; i++ {
+		c := src[i]
+		switch {
+		case c == '-' && i+1 < n && src[i+1] == '-':
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case c == '/' && i+1 < n && src[i+1] == '*':
+			i += 2
+			for i+1 < n && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i++
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case c == '\'':
+			// строковый литерал, '' внутри - экранированная кавычка
+			for i++; i < n; i++ {
+				if src[i] == '\'' {
+					if i+1 < n && src[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+		case c == '"':
+			// идентификатор в кавычках копируем как есть
+			b.WriteRune(c)
+			for i++; i < n; i++ {
+				b.WriteRune(src[i])
+				if src[i] == '"' {
+					break
+				}
+			}
+		case c == '$' && i+1 < n && isDigit(src[i+1]):
+			for i+1 < n && isDigit(src[i+1]) {
+				i++
+			}
+			b.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isIdentRune(src[i-1])):
+			for i+1 < n && (isDigit(src[i+1]) || src[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			b.WriteRune(c)
+		}
+	}
+
+	return inListRe.ReplaceAllString(b.String(), "in (...)")
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || isDigit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/seemyown/backend-toolkit/btools/logging"
+)
+
+// QueryKind - тип операции, о которой сообщает QueryEvent.
+type QueryKind string
+
+const (
+	QueryKindQuery       QueryKind = "query"
+	QueryKindExec        QueryKind = "exec"
+	QueryKindTransaction QueryKind = "transaction"
+)
+
+// QueryEvent описывает выполненный запрос, exec или транзакцию.
+type QueryEvent struct {
+	Kind         QueryKind
+	Query        string
+	Fingerprint  string // нормализованный запрос без литералов, см. Fingerprint
+	Args         []interface{}
+	StartedAt    time.Time
+	Duration     time.Duration
+	RowsAffected int64 // -1, если неизвестно
+	Err          error
+	ErrorCode    string // SQLSTATE, если ошибка пришла от Postgres
+}
+
+// QueryHook оборачивает каждый запрос через Database и каждую транзакцию Transaction.
+// BeforeQuery может вернуть новый контекст (например, со span'ом трассировки),
+// он же будет передан в AfterQuery.
+//
+// Запросы внутри транзакции и запросы к *sqlx.DB, принадлежащему Database, хуки видят,
+// если они идут через функции пакета: SelectOne, SelectMany, SelectIn, *Named,
+// PrepareAndExec и методы BaseRepository. Прямые вызовы методов *sqlx.Tx и *sqlx.DB,
+// Stream, Listen, Migrator и блокировки через хуки не проходят.
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// AddHook регистрирует хуки. Вызывать до начала работы с базой: список хуков не защищён мьютексом.
+func (d *Database) AddHook(hooks ...QueryHook) {
+	d.hooks = append(d.hooks, hooks...)
+}
+
+func (d *Database) observe(
+	ctx context.Context,
+	kind QueryKind,
+	query string,
+	args []interface{},
+	fn func(ctx context.Context) (int64, error),
+) error {
+	if len(d.hooks) == 0 {
+		_, err := fn(ctx)
+		return err
+	}
+
+	event := &QueryEvent{
+		Kind:         kind,
+		Query:        query,
+		Fingerprint:  Fingerprint(query),
+		Args:         args,
+		StartedAt:    time.Now(),
+		RowsAffected: -1,
+	}
+	for _, h := range d.hooks {
+		ctx = h.BeforeQuery(ctx, event)
+	}
+
+	rows, err := fn(ctx)
+
+	event.Duration = time.Since(event.StartedAt)
+	event.RowsAffected = rows
+	event.Err = err
+	event.ErrorCode = sqlState(err)
+	for i := len(d.hooks) - 1; i >= 0; i-- {
+		d.hooks[i].AfterQuery(ctx, event)
+	}
+	return err
+}
+
+// databases - подключения (основной сервер и реплики) и Database, которому они принадлежат.
+var databases sync.Map
+
+// owner возвращает Database, через хуки которого должны идти запросы к q:
+// для *sqlx.Tx из Transaction.Exec и для *sqlx.DB, открытого через Database.
+func owner(q any) *Database {
+	switch q := q.(type) {
+	case *sqlx.Tx:
+		if v, ok := activeTxs.Load(q); ok {
+			return v.(*activeTx).db
+		}
+	case *sqlx.DB:
+		if v, ok := databases.Load(q); ok {
+			return v.(*Database)
+		}
+	}
+	return nil
+}
+
+// instrument оборачивает *sqlx.Tx или *sqlx.DB так, чтобы запросы через него проходили
+// через хуки Database. Остальные источники, в том числе сам *Database, возвращаются как есть.
+func instrument[Q any](q Q) Q {
+	d := owner(q)
+	if d == nil || len(d.hooks) == 0 {
+		return q
+	}
+	if o, ok := any(&observedQuerier{db: d, q: any(q).(sqlx.ExtContext)}).(Q); ok {
+		return o
+	}
+	return q
+}
+
+// observedQuerier выполняет запросы на q без выбора реплики и арендатора, но с хуками db.
+type observedQuerier struct {
+	db *Database
+	q  sqlx.ExtContext
+}
+
+func (o *observedQuerier) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return o.db.observe(ctx, QueryKindQuery, query, args, func(ctx context.Context) (int64, error) {
+		if err := sqlx.GetContext(ctx, o.q, dest, query, args...); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+}
+
+func (o *observedQuerier) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return o.db.observe(ctx, QueryKindQuery, query, args, func(ctx context.Context) (int64, error) {
+		if err := sqlx.SelectContext(ctx, o.q, dest, query, args...); err != nil {
+			return 0, err
+		}
+		return destLen(dest), nil
+	})
+}
+
+func (o *observedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := o.db.observe(ctx, QueryKindExec, query, args, func(ctx context.Context) (int64, error) {
+		var err error
+		if result, err = o.q.ExecContext(ctx, query, args...); err != nil {
+			return 0, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return -1, nil
+		}
+		return rows, nil
+	})
+	return result, err
+}
+
+func (o *observedQuerier) Rebind(query string) string {
+	return o.q.Rebind(query)
+}
+
+func sqlState(err error) string {
+	if pgErr, ok := asPgError(err); ok {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func destLen(dest interface{}) int64 {
+	v := reflect.ValueOf(dest)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		return int64(v.Len())
+	}
+	return -1
+}
+
+// SlowQueryLogger пишет в лог запросы, выполнявшиеся дольше Threshold, и все запросы с ошибкой.
+type SlowQueryLogger struct {
+	Threshold time.Duration
+	Logger    *logging.Logger
+}
+
+func NewSlowQueryLogger(threshold time.Duration, logger *logging.Logger) *SlowQueryLogger {
+	if logger == nil {
+		logger = log.NewSubLogger("slow_query")
+	}
+	return &SlowQueryLogger{Threshold: threshold, Logger: logger}
+}
+
+func (l *SlowQueryLogger) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (l *SlowQueryLogger) AfterQuery(_ context.Context, event *QueryEvent) {
+	if event.Err != nil {
+		l.Logger.Error(event.Err, "%s failed in %s code=%s: %s", event.Kind, event.Duration, event.ErrorCode, event.Fingerprint)
+		return
+	}
+	if event.Duration >= l.Threshold {
+		l.Logger.Warn("slow %s %s rows=%d: %s", event.Kind, event.Duration, event.RowsAffected, event.Fingerprint)
+	}
+}
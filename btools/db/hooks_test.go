@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM users WHERE id = $1":                          "select * from users where id = ?",
+		"select  *\n\tfrom t1 where name = 'O''Brien' and age > 42":  "select * from t1 where name = ? and age > ?",
+		"SELECT id FROM users WHERE id IN (?, ?, ?) -- комментарий":  "select id from users where id in (...)",
+		`SELECT /* hint */ "UserID" FROM orders WHERE total >= 10.5`: `select "UserID" from orders where total >= ?`,
+		"UPDATE users SET status = $2 WHERE id = ANY($1)":            "update users set status = ? where id = any(?)",
+	}
+	for query, expected := range cases {
+		if got := Fingerprint(query); got != expected {
+			t.Errorf("Fingerprint(%q) = %q, ожидалось %q", query, got, expected)
+		}
+	}
+}
+
+type recordingHook struct {
+	events []*QueryEvent
+}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *recordingHook) AfterQuery(_ context.Context, event *QueryEvent) {
+	h.events = append(h.events, event)
+}
+
+func TestDatabaseObserve(t *testing.T) {
+	hook := &recordingHook{}
+	d := &Database{}
+	d.AddHook(hook)
+
+	pgErr := &pq.Error{Code: "23505"}
+	err := d.observe(context.Background(), QueryKindExec, "DELETE FROM users WHERE id = $1", []interface{}{1},
+		func(ctx context.Context) (int64, error) {
+			return 0, pgErr
+		})
+	if !errors.Is(err, pgErr) {
+		t.Fatalf("observe должен вернуть ошибку fn, а вернул %v", err)
+	}
+	if len(hook.events) != 1 {
+		t.Fatalf("Ожидалось одно событие, а получили %d", len(hook.events))
+	}
+	event := hook.events[0]
+	if event.ErrorCode != "23505" || event.Fingerprint != "delete from users where id = ?" || event.Kind != QueryKindExec {
+		t.Errorf("Неверное событие: %+v", event)
+	}
+}
+
+func TestHooksInsideTransaction(t *testing.T) {
+	hook := &recordingHook{}
+	conn := openStreamDB(t)
+	d := NewDatabaseFromDB(conn, hook)
+	ctx := context.Background()
+
+	err := NewTrx(d).Exec(ctx, func(tx *sqlx.Tx) error {
+		if _, err := SelectMany[streamUser](tx, ctx, "SELECT id, name FROM users"); err != nil {
+			return err
+		}
+		if _, err := ExecNamed(ctx, tx, "DELETE FROM users WHERE id = :id", map[string]interface{}{"id": 1}); err != nil {
+			return err
+		}
+		return PrepareAndExec(ctx, tx, "INSERT INTO users (name) VALUES ($1)", []*streamUser{{Name: "a"}, {Name: "b"}},
+			func(stmt *sqlx.Stmt, u *streamUser) error {
+				_, err := stmt.ExecContext(ctx, u.Name)
+				return err
+			})
+	})
+	if err != nil {
+		t.Fatalf("Транзакция завершилась ошибкой: %v", err)
+	}
+	// запрос напрямую к *sqlx.DB, принадлежащему Database
+	if _, err := SelectOne[streamUser](conn, ctx, "SELECT id, name FROM users"); err != nil {
+		t.Fatalf("SelectOne вернул ошибку: %v", err)
+	}
+
+	var kinds []string
+	for _, event := range hook.events {
+		kinds = append(kinds, string(event.Kind))
+	}
+	expected := "query,exec,exec,exec,transaction,query"
+	if strings.Join(kinds, ",") != expected {
+		t.Fatalf("Ожидались события %s, а получили %v", expected, kinds)
+	}
+	if hook.events[0].RowsAffected != 5 || hook.events[1].Fingerprint != "delete from users where id = ?" {
+		t.Errorf("Неверные события внутри транзакции: %+v, %+v", hook.events[0], hook.events[1])
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	m := NewMetricsHook("app_db", []float64{0.1, 1})
+	ctx := context.Background()
+	m.AfterQuery(ctx, &QueryEvent{Kind: QueryKindQuery, Duration: 50 * time.Millisecond, RowsAffected: 3})
+	m.AfterQuery(ctx, &QueryEvent{Kind: QueryKindQuery, Duration: 500 * time.Millisecond, RowsAffected: 2})
+	m.AfterQuery(ctx, &QueryEvent{Kind: QueryKindExec, Duration: 2 * time.Second, Err: errors.New("boom"), ErrorCode: "40P01"})
+
+	var b strings.Builder
+	if err := m.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus вернула ошибку: %v", err)
+	}
+	out := b.String()
+	expected := []string{
+		`app_db_query_duration_seconds_bucket{kind="query",status="ok",le="0.1"} 1`,
+		`app_db_query_duration_seconds_bucket{kind="query",status="ok",le="1"} 2`,
+		`app_db_query_duration_seconds_count{kind="query",status="ok"} 2`,
+		`app_db_query_duration_seconds_bucket{kind="exec",status="error",le="1"} 0`,
+		`app_db_query_duration_seconds_bucket{kind="exec",status="error",le="+Inf"} 1`,
+		`app_db_rows_affected_total{kind="query"} 5`,
+		`app_db_errors_total{code="40P01"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("В выводе метрик нет строки %q:\n%s", line, out)
+		}
+	}
+}
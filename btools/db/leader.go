@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+var leaderLogger = log.NewSubLogger("leader")
+
+type LeaderConfig struct {
+	// RetryInterval - как часто follower пытается стать лидером (по умолчанию 5s)
+	RetryInterval time.Duration
+	// CheckInterval - как часто лидер проверяет, что соединение с блокировкой живо (по умолчанию 5s)
+	CheckInterval time.Duration
+	// OnElected вызывается в отдельной горутине при получении лидерства.
+	// ctx отменяется при потере лидерства или остановке Run; блокировка отпускается
+	// и OnRevoked вызывается только после возврата OnElected, чтобы работа двух лидеров не пересекалась.
+	OnElected func(ctx context.Context)
+	// OnRevoked вызывается при потере лидерства.
+	OnRevoked func()
+}
+
+// LeaderElection выбирает одного лидера среди реплик сервиса на основе session-level advisory lock.
+type LeaderElection struct {
+	db     *Database
+	name   string
+	config LeaderConfig
+	leader atomic.Bool
+}
+
+func NewLeaderElection(d *Database, name string, config LeaderConfig) *LeaderElection {
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = 5 * time.Second
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = 5 * time.Second
+	}
+	return &LeaderElection{db: d, name: name, config: config}
+}
+
+func (e *LeaderElection) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run участвует в выборах, пока не будет отменён ctx. Блокирующий вызов.
+func (e *LeaderElection) Run(ctx context.Context) error {
+	for {
+		lock, acquired, err := e.db.TryLock(ctx, e.name)
+		if err != nil {
+			leaderLogger.Error(err, "leader election %s: failed to try lock", e.name)
+		} else if acquired {
+			e.lead(ctx, lock)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.config.RetryInterval):
+		}
+	}
+}
+
+// lead держит лидерство, пока живо соединение с блокировкой и не отменён ctx.
+func (e *LeaderElection) lead(ctx context.Context, lock *SessionLock) {
+	leaderLogger.Info("leader election %s: became leader", e.name)
+	e.leader.Store(true)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	elected := make(chan struct{})
+	if e.config.OnElected != nil {
+		go func() {
+			defer close(elected)
+			e.config.OnElected(leaderCtx)
+		}()
+	} else {
+		close(elected)
+	}
+
+	ticker := time.NewTicker(e.config.CheckInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			if err := lock.Ping(ctx); err != nil {
+				leaderLogger.Error(err, "leader election %s: lost lock connection", e.name)
+				break loop
+			}
+		}
+	}
+
+	cancel()
+	e.leader.Store(false)
+	// пока OnElected не завершился, другой экземпляр не должен стать лидером
+	<-elected
+	unlockCtx, unlockCancel := context.WithTimeout(context.Background(), e.config.CheckInterval)
+	defer unlockCancel()
+	if err := lock.Unlock(unlockCtx); err != nil {
+		leaderLogger.Error(err, "leader election %s: failed to release lock", e.name)
+	}
+	leaderLogger.Info("leader election %s: leadership revoked", e.name)
+	if e.config.OnRevoked != nil {
+		e.config.OnRevoked()
+	}
+}
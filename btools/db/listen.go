@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var listenLogger = log.NewSubLogger("listen")
+
+// Notification - уведомление NOTIFY с декодированным payload.
+// Reconnected выставляется после переподключения: уведомления, пришедшие во время разрыва,
+// потеряны, и подписчику стоит сбросить состояние (например, весь кэш).
+type Notification[T any] struct {
+	Channel     string
+	Payload     T
+	Reconnected bool
+	Err         error // ошибка декодирования payload
+}
+
+type ListenConfig struct {
+	MinReconnectInterval time.Duration // по умолчанию 1s
+	MaxReconnectInterval time.Duration // по умолчанию 1m
+	PingInterval         time.Duration // по умолчанию 90s
+	BufferSize           int           // размер буфера канала, по умолчанию 64
+}
+
+func (c *ListenConfig) withDefaults() ListenConfig {
+	cfg := *c
+	if cfg.MinReconnectInterval <= 0 {
+		cfg.MinReconnectInterval = time.Second
+	}
+	if cfg.MaxReconnectInterval <= 0 {
+		cfg.MaxReconnectInterval = time.Minute
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 90 * time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 64
+	}
+	return cfg
+}
+
+// Listen подписывается на канал LISTEN и возвращает канал уведомлений с payload,
+// декодированным в T (string и []byte - как есть, остальное - из JSON).
+// Переподключение и повторная подписка выполняются автоматически; канал закрывается после отмены ctx.
+func Listen[T any](ctx context.Context, d *Database, channel string) (<-chan Notification[T], error) {
+	return ListenWithConfig[T](ctx, d, channel, ListenConfig{})
+}
+
+func ListenWithConfig[T any](ctx context.Context, d *Database, channel string, cfg ListenConfig) (<-chan Notification[T], error) {
+	if d.dsn == "" {
+		return nil, errors.New("listen requires a database created with NewDatabase")
+	}
+	cfg = cfg.withDefaults()
+
+	listener := pq.NewListener(d.dsn, cfg.MinReconnectInterval, cfg.MaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			switch event {
+			case pq.ListenerEventDisconnected:
+				listenLogger.Error(err, "listener on %s disconnected", channel)
+			case pq.ListenerEventReconnected:
+				listenLogger.Info("listener on %s reconnected", channel)
+			case pq.ListenerEventConnectionAttemptFailed:
+				listenLogger.Error(err, "listener on %s failed to reconnect", channel)
+			}
+		})
+	if err := subscribe(ctx, listener, channel); err != nil {
+		return nil, err
+	}
+	return forward[T](ctx, listener, channel, cfg), nil
+}
+
+// notificationListener - то, чем Listen пользуется у *pq.Listener.
+type notificationListener interface {
+	Listen(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+// subscribe выполняет LISTEN с учётом ctx: pq.Listener.Listen ждёт первого соединения
+// и не знает о контексте, поэтому при недоступной базе ожидание прерывается через Close.
+func subscribe(ctx context.Context, listener notificationListener, channel string) error {
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- listener.Listen(channel)
+	}()
+	select {
+	case err := <-subscribed:
+		if err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("listen %s: %w", channel, err)
+		}
+		return nil
+	case <-ctx.Done():
+		_ = listener.Close()
+		return ctx.Err()
+	}
+}
+
+// forward переправляет уведомления listener в канал, пингует соединение раз в PingInterval
+// и закрывает listener и канал после отмены ctx.
+func forward[T any](ctx context.Context, listener notificationListener, channel string, cfg ListenConfig) <-chan Notification[T] {
+	out := make(chan Notification[T], cfg.BufferSize)
+	go func() {
+		defer close(out)
+		defer func() {
+			if err := listener.Close(); err != nil {
+				listenLogger.Error(err, "failed to close listener on %s", channel)
+			}
+		}()
+
+		ticker := time.NewTicker(cfg.PingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := listener.Ping(); err != nil {
+					listenLogger.Error(err, "listener on %s ping failed", channel)
+				}
+			case n := <-listener.NotificationChannel():
+				var notification Notification[T]
+				if n == nil {
+					// lib/pq присылает nil после переподключения
+					notification = Notification[T]{Channel: channel, Reconnected: true}
+				} else {
+					notification = Notification[T]{Channel: n.Channel}
+					notification.Payload, notification.Err = decodePayload[T](n.Extra)
+				}
+				select {
+				case out <- notification:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Notify отправляет уведомление в канал. payload кодируется так же, как декодирует Listen.
+func (d *Database) Notify(ctx context.Context, channel string, payload any) error {
+	var data string
+	switch v := payload.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("marshal notify payload: %w", err)
+		}
+		data = string(raw)
+	}
+	if _, err := d.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, data); err != nil {
+		return WrapError(err)
+	}
+	return nil
+}
+
+func decodePayload[T any](raw string) (T, error) {
+	var payload T
+	switch p := any(&payload).(type) {
+	case *string:
+		*p = raw
+		return payload, nil
+	case *[]byte:
+		*p = []byte(raw)
+		return payload, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return payload, fmt.Errorf("decode notify payload: %w", err)
+	}
+	return payload, nil
+}
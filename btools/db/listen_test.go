@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type cacheEvent struct {
+	Key string `json:"key"`
+}
+
+func TestDecodePayload(t *testing.T) {
+	s, err := decodePayload[string]("users:42")
+	if err != nil || s != "users:42" {
+		t.Errorf("Строка должна передаваться как есть, а получили %q, %v", s, err)
+	}
+
+	b, err := decodePayload[[]byte]("raw")
+	if err != nil || string(b) != "raw" {
+		t.Errorf("Байты должны передаваться как есть, а получили %q, %v", b, err)
+	}
+
+	event, err := decodePayload[cacheEvent](`{"key":"users:42"}`)
+	if err != nil || event.Key != "users:42" {
+		t.Errorf("Ожидался декодированный JSON, а получили %+v, %v", event, err)
+	}
+
+	if _, err := decodePayload[cacheEvent]("not json"); err == nil {
+		t.Errorf("Ожидалась ошибка декодирования")
+	}
+}
+
+// fakeListener подменяет *pq.Listener: уведомления присылает тест, пинги и закрытие отмечаются в каналах
+type fakeListener struct {
+	// down - база недоступна: Listen ждёт соединения, пока listener не закроют
+	down   bool
+	notify chan *pq.Notification
+	pings  chan struct{}
+	closed chan struct{}
+}
+
+func newFakeListener() *fakeListener {
+	return &fakeListener{
+		notify: make(chan *pq.Notification),
+		pings:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *fakeListener) Listen(string) error {
+	if l.down {
+		<-l.closed
+		return errors.New("listener closed")
+	}
+	return nil
+}
+
+func (l *fakeListener) NotificationChannel() <-chan *pq.Notification { return l.notify }
+
+func (l *fakeListener) Ping() error {
+	select {
+	case l.pings <- struct{}{}:
+	default:
+	}
+	return errors.New("ping failed")
+}
+
+func (l *fakeListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func TestListenForward(t *testing.T) {
+	listener := newFakeListener()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// пинг раз в час не должен мешать тесту
+	out := forward[cacheEvent](ctx, listener, "cache", ListenConfig{PingInterval: time.Hour, BufferSize: 1})
+
+	listener.notify <- &pq.Notification{Channel: "cache", Extra: `{"key":"users:42"}`}
+	n := waitFor(t, out, "уведомления")
+	if n.Channel != "cache" || n.Payload.Key != "users:42" || n.Err != nil || n.Reconnected {
+		t.Errorf("Ожидалось декодированное уведомление, а получили %+v", n)
+	}
+
+	listener.notify <- &pq.Notification{Channel: "cache", Extra: "not json"}
+	if n := waitFor(t, out, "уведомления с ошибкой"); n.Err == nil {
+		t.Errorf("Ожидалась ошибка декодирования payload")
+	}
+
+	// после переподключения lib/pq присылает nil: подписчик должен узнать о пропущенных уведомлениях
+	listener.notify <- nil
+	if n := waitFor(t, out, "уведомления о переподключении"); !n.Reconnected || n.Channel != "cache" {
+		t.Errorf("Ожидалось уведомление о переподключении, а получили %+v", n)
+	}
+}
+
+func TestListenPing(t *testing.T) {
+	listener := newFakeListener()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := forward[string](ctx, listener, "cache", ListenConfig{PingInterval: time.Millisecond, BufferSize: 1})
+
+	// ошибка пинга только логируется, соединение восстанавливает сам pq.Listener
+	waitFor(t, listener.pings, "пинга")
+	waitFor(t, listener.pings, "повторного пинга")
+
+	listener.notify <- &pq.Notification{Channel: "cache", Extra: "users:42"}
+	if n := waitFor(t, out, "уведомления"); n.Payload != "users:42" {
+		t.Errorf("После неудачного пинга уведомления должны доходить, а получили %+v", n)
+	}
+}
+
+func TestListenCancel(t *testing.T) {
+	listener := newFakeListener()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := forward[string](ctx, listener, "cache", ListenConfig{PingInterval: time.Hour, BufferSize: 1})
+
+	// буфер заполнен, и отправка следующего уведомления ждёт читателя
+	listener.notify <- &pq.Notification{Channel: "cache", Extra: "first"}
+	listener.notify <- &pq.Notification{Channel: "cache", Extra: "second"}
+	cancel()
+
+	waitFor(t, listener.closed, "закрытия listener")
+	for n := range out {
+		if n.Payload != "first" {
+			t.Errorf("После отмены ctx в канале может остаться только буферизованное уведомление, а получили %+v", n)
+		}
+	}
+}
+
+func TestListenSubscribeCancel(t *testing.T) {
+	listener := newFakeListener()
+	listener.down = true
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- subscribe(ctx, listener, "cache") }()
+	cancel()
+
+	if err := waitFor(t, done, "возврата из subscribe"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Ожидалась context.Canceled, а получили %v", err)
+	}
+	waitFor(t, listener.closed, "закрытия listener")
+
+	if err := subscribe(context.Background(), newFakeListener(), "cache"); err != nil {
+		t.Errorf("При доступной базе subscribe не должен возвращать ошибку: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var lockLogger = log.NewSubLogger("lock")
+
+// LockKey переводит строковое имя блокировки в ключ pg_advisory_lock.
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// SessionLock - session-level advisory lock. Держит выделенное соединение из пула,
+// пока блокировка не будет снята через Unlock.
+type SessionLock struct {
+	Name string
+
+	key  int64
+	conn *sqlx.Conn
+	once sync.Once
+	err  error
+}
+
+// TryLock пытается взять блокировку без ожидания. Если она занята, возвращает nil, false, nil.
+func (d *Database) TryLock(ctx context.Context, name string) (*SessionLock, bool, error) {
+	conn, err := d.DB.Connx(ctx)
+	if err != nil {
+		return nil, false, WrapError(err)
+	}
+
+	key := LockKey(name)
+	var acquired bool
+	if err := conn.GetContext(ctx, &acquired, "SELECT pg_try_advisory_lock($1)", key); err != nil {
+		discardConn(conn)
+		return nil, false, WrapError(err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+	return &SessionLock{Name: name, key: key, conn: conn}, true, nil
+}
+
+// Lock ждёт блокировку, пока её не отпустят или не истечёт ctx.
+func (d *Database) Lock(ctx context.Context, name string) (*SessionLock, error) {
+	conn, err := d.DB.Connx(ctx)
+	if err != nil {
+		return nil, WrapError(err)
+	}
+
+	key := LockKey(name)
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		// при отмене запроса блокировка могла успеть взяться - соединение в пул не возвращаем
+		discardConn(conn)
+		lockLogger.Error(err, "failed to acquire lock %s", name)
+		return nil, WrapError(err)
+	}
+	return &SessionLock{Name: name, key: key, conn: conn}, nil
+}
+
+// Unlock снимает блокировку и возвращает соединение в пул. Повторные вызовы ничего не делают.
+func (l *SessionLock) Unlock(ctx context.Context) error {
+	l.once.Do(func() {
+		var released bool
+		if err := l.conn.GetContext(ctx, &released, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+			discardConn(l.conn)
+			l.err = WrapError(err)
+			return
+		}
+		if !released {
+			lockLogger.Warn("lock %s was not held by the session", l.Name)
+		}
+		l.err = l.conn.Close()
+	})
+	return l.err
+}
+
+// Ping проверяет, что соединение с блокировкой живо. Если нет - блокировка потеряна.
+func (l *SessionLock) Ping(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT 1")
+	return err
+}
+
+// TryLockTx пытается взять transaction-level блокировку; она снимается по завершении транзакции.
+func TryLockTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	var acquired bool
+	if err := tx.GetContext(ctx, &acquired, "SELECT pg_try_advisory_xact_lock($1)", LockKey(name)); err != nil {
+		return false, WrapError(err)
+	}
+	return acquired, nil
+}
+
+// LockTx ждёт transaction-level блокировку, пока её не отпустят или не истечёт ctx.
+func LockTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", LockKey(name)); err != nil {
+		return WrapError(err)
+	}
+	return nil
+}
+
+// discardConn закрывает физическое соединение вместо возврата в пул,
+// чтобы вместе с ним гарантированно пропали session-level блокировки.
+func discardConn(conn *sqlx.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
@@ -0,0 +1,515 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lockDriver - драйвер database/sql, который эмулирует advisory locks Postgres
+// и таблицу миграций. У каждого DSN своё состояние, чтобы тесты не мешали друг другу.
+type lockDriver struct {
+	mu      sync.Mutex
+	servers map[string]*lockServer
+}
+
+var testLockDriver = &lockDriver{servers: make(map[string]*lockServer)}
+
+func init() {
+	sql.Register("btools-lock", testLockDriver)
+}
+
+func (d *lockDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	server := d.servers[name]
+	d.mu.Unlock()
+	return &lockConn{server: server}, nil
+}
+
+var errConnLost = errors.New("connection lost")
+
+// lockServer - состояние одной "базы": кто держит блокировки и какие миграции применены
+type lockServer struct {
+	mu       sync.Mutex
+	holders  map[int64]*lockConn
+	released chan struct{}
+	// waiting получает ключ, когда соединение встаёт в ожидание pg_advisory_lock
+	waiting chan int64
+	conns   []*lockConn
+	closed  int
+	applied map[int64]string
+	scripts []string
+}
+
+func openLockDB(t *testing.T) (*Database, *lockServer) {
+	server := &lockServer{
+		holders:  make(map[int64]*lockConn),
+		released: make(chan struct{}),
+		waiting:  make(chan int64, 16),
+		applied:  make(map[int64]string),
+	}
+	testLockDriver.mu.Lock()
+	testLockDriver.servers[t.Name()] = server
+	testLockDriver.mu.Unlock()
+
+	conn, err := sqlx.Open("btools-lock", t.Name())
+	if err != nil {
+		t.Fatalf("Не удалось открыть тестовый драйвер: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewDatabaseFromDB(conn), server
+}
+
+// holder возвращает соединение, которое держит блокировку name, или nil
+func (s *lockServer) holder(name string) *lockConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.holders[LockKey(name)]
+}
+
+func (s *lockServer) closedConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// kill обрывает все открытые соединения, как при рестарте базы
+func (s *lockServer) kill() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.dead = true
+		s.releaseAll(conn)
+	}
+}
+
+func (s *lockServer) releaseAll(conn *lockConn) {
+	for key, holder := range s.holders {
+		if holder == conn {
+			delete(s.holders, key)
+		}
+	}
+	close(s.released)
+	s.released = make(chan struct{})
+}
+
+type lockConn struct {
+	server *lockServer
+	dead   bool
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *lockConn) Begin() (driver.Tx, error)           { return lockTx{}, nil }
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.server.closed++
+	c.server.releaseAll(c)
+	return nil
+}
+
+// alive регистрирует соединение на "сервере" и проверяет, что оно не оборвано
+func (c *lockConn) alive() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.dead {
+		return errConnLost
+	}
+	for _, conn := range c.server.conns {
+		if conn == c {
+			return nil
+		}
+	}
+	c.server.conns = append(c.server.conns, c)
+	return nil
+}
+
+func (c *lockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.alive(); err != nil {
+		return nil, err
+	}
+	s := c.server
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_advisory_lock"):
+		key := args[0].Value.(int64)
+		for {
+			s.mu.Lock()
+			if holder, ok := s.holders[key]; !ok || holder == c {
+				s.holders[key] = c
+				s.mu.Unlock()
+				return driver.RowsAffected(0), nil
+			}
+			released := s.released
+			s.mu.Unlock()
+			s.waiting <- key
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-released:
+			}
+		}
+	case query == "SELECT 1", strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS"):
+	case strings.HasPrefix(query, "INSERT INTO"):
+		s.mu.Lock()
+		s.applied[args[0].Value.(int64)] = args[2].Value.(string)
+		s.mu.Unlock()
+	case strings.HasPrefix(query, "DELETE FROM"):
+		s.mu.Lock()
+		delete(s.applied, args[0].Value.(int64))
+		s.mu.Unlock()
+	default:
+		s.mu.Lock()
+		s.scripts = append(s.scripts, query)
+		s.mu.Unlock()
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *lockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.alive(); err != nil {
+		return nil, err
+	}
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_try_advisory_lock"):
+		key := args[0].Value.(int64)
+		holder, held := s.holders[key]
+		if !held {
+			s.holders[key] = c
+		}
+		return &lockRows{columns: []string{"acquired"}, values: [][]driver.Value{{!held || holder == c}}}, nil
+	case strings.HasPrefix(query, "SELECT pg_advisory_unlock"):
+		key := args[0].Value.(int64)
+		holder, held := s.holders[key]
+		if held && holder == c {
+			delete(s.holders, key)
+			close(s.released)
+			s.released = make(chan struct{})
+		}
+		return &lockRows{columns: []string{"released"}, values: [][]driver.Value{{held && holder == c}}}, nil
+	case strings.HasPrefix(query, "SELECT version, checksum, applied_at"):
+		rows := &lockRows{columns: []string{"version", "checksum", "applied_at"}}
+		for version, checksum := range s.applied {
+			rows.values = append(rows.values, []driver.Value{version, checksum, time.Now()})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type lockTx struct{}
+
+func (lockTx) Commit() error   { return nil }
+func (lockTx) Rollback() error { return nil }
+
+type lockRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *lockRows) Columns() []string { return r.columns }
+func (r *lockRows) Close() error      { return nil }
+
+func (r *lockRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestLockKey(t *testing.T) {
+	if LockKey("jobs.cleanup") != LockKey("jobs.cleanup") {
+		t.Errorf("Ключ блокировки должен быть детерминированным")
+	}
+	if LockKey("jobs.cleanup") == LockKey("jobs.report") {
+		t.Errorf("Разные имена должны давать разные ключи")
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	d, server := openLockDB(t)
+	ctx := context.Background()
+
+	lock, acquired, err := d.TryLock(ctx, "jobs.cleanup")
+	if err != nil || !acquired {
+		t.Fatalf("Свободная блокировка должна браться: %v, %v", acquired, err)
+	}
+	if server.holder("jobs.cleanup") == nil {
+		t.Errorf("Блокировку должно держать соединение")
+	}
+
+	other, acquired, err := d.TryLock(ctx, "jobs.cleanup")
+	if err != nil || acquired || other != nil {
+		t.Errorf("Занятая блокировка не должна браться: %v, %v, %v", other, acquired, err)
+	}
+	if server.closedConns() != 0 {
+		t.Errorf("После неудачной попытки соединение должно вернуться в пул, а закрыто %d", server.closedConns())
+	}
+
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock вернул ошибку: %v", err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Errorf("Повторный Unlock не должен возвращать ошибку: %v", err)
+	}
+	if server.holder("jobs.cleanup") != nil {
+		t.Errorf("После Unlock блокировка должна быть свободна")
+	}
+
+	lock, acquired, err = d.TryLock(ctx, "jobs.cleanup")
+	if err != nil || !acquired {
+		t.Fatalf("Отпущенная блокировка должна браться снова: %v, %v", acquired, err)
+	}
+	_ = lock.Unlock(ctx)
+}
+
+func TestLockWaits(t *testing.T) {
+	d, server := openLockDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	first, err := d.Lock(ctx, "jobs.cleanup")
+	if err != nil {
+		t.Fatalf("Lock вернул ошибку: %v", err)
+	}
+
+	done := make(chan *SessionLock, 1)
+	go func() {
+		second, err := d.Lock(ctx, "jobs.cleanup")
+		if err != nil {
+			t.Errorf("Lock вернул ошибку: %v", err)
+		}
+		done <- second
+	}()
+	<-server.waiting
+	select {
+	case <-done:
+		t.Fatal("Lock не должен возвращаться, пока блокировка занята")
+	default:
+	}
+
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock вернул ошибку: %v", err)
+	}
+	second := <-done
+	if second == nil || server.holder("jobs.cleanup") == nil {
+		t.Fatalf("После Unlock блокировку должен получить ожидающий")
+	}
+	_ = second.Unlock(ctx)
+}
+
+func TestLockCancelled(t *testing.T) {
+	d, server := openLockDB(t)
+	first, err := d.Lock(context.Background(), "jobs.cleanup")
+	if err != nil {
+		t.Fatalf("Lock вернул ошибку: %v", err)
+	}
+	defer func() { _ = first.Unlock(context.Background()) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.Lock(ctx, "jobs.cleanup")
+		done <- err
+	}()
+	<-server.waiting
+	cancel()
+
+	if err := <-done; err == nil {
+		t.Fatal("Ожидалась ошибка после отмены контекста")
+	}
+	// соединение могло успеть взять блокировку, поэтому оно закрывается, а не уходит в пул
+	if server.closedConns() != 1 {
+		t.Errorf("Ожидалось одно закрытое соединение, а закрыто %d", server.closedConns())
+	}
+}
+
+func TestDiscardConn(t *testing.T) {
+	d, server := openLockDB(t)
+	ctx := context.Background()
+	key := LockKey("jobs.cleanup")
+
+	conn, err := d.DB.Connx(ctx)
+	if err != nil {
+		t.Fatalf("Connx вернул ошибку: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		t.Fatalf("Не удалось взять блокировку: %v", err)
+	}
+	// обычный Close вернул бы соединение в пул вместе с session-level блокировкой
+	discardConn(conn)
+
+	if server.closedConns() != 1 {
+		t.Errorf("discardConn должен закрывать физическое соединение, а закрыто %d", server.closedConns())
+	}
+	if server.holder("jobs.cleanup") != nil {
+		t.Errorf("Вместе с соединением должна пропасть и блокировка")
+	}
+}
+
+func TestUnlockLostConnection(t *testing.T) {
+	d, server := openLockDB(t)
+	ctx := context.Background()
+
+	lock, err := d.Lock(ctx, "jobs.cleanup")
+	if err != nil {
+		t.Fatalf("Lock вернул ошибку: %v", err)
+	}
+	server.kill()
+
+	if err := lock.Ping(ctx); err == nil {
+		t.Errorf("Ping должен сообщать о потерянном соединении")
+	}
+	if err := lock.Unlock(ctx); err == nil {
+		t.Errorf("Unlock на потерянном соединении должен вернуть ошибку")
+	}
+	if server.closedConns() != 1 {
+		t.Errorf("Потерянное соединение не должно возвращаться в пул, а закрыто %d", server.closedConns())
+	}
+}
+
+func TestNewLeaderElectionDefaults(t *testing.T) {
+	e := NewLeaderElection(&Database{}, "jobs.cleanup", LeaderConfig{})
+	if e.config.RetryInterval <= 0 || e.config.CheckInterval <= 0 {
+		t.Errorf("Ожидались интервалы по умолчанию, а получили %+v", e.config)
+	}
+	if e.IsLeader() {
+		t.Errorf("До запуска Run экземпляр не может быть лидером")
+	}
+}
+
+func TestLeaderElection(t *testing.T) {
+	d, server := openLockDB(t)
+	elected := make(chan context.Context, 2)
+	revoked := make(chan struct{}, 2)
+	e := NewLeaderElection(d, "jobs.cleanup", LeaderConfig{
+		RetryInterval: time.Millisecond,
+		CheckInterval: time.Millisecond,
+		OnElected:     func(ctx context.Context) { elected <- ctx },
+		OnRevoked:     func() { revoked <- struct{}{} },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Run(ctx) }()
+
+	leaderCtx := waitFor(t, elected, "получения лидерства")
+	if !e.IsLeader() || server.holder("jobs.cleanup") == nil {
+		t.Errorf("После OnElected экземпляр должен быть лидером и держать блокировку")
+	}
+
+	// второй участник не становится лидером, пока блокировка занята
+	if lock, acquired, _ := d.TryLock(context.Background(), "jobs.cleanup"); acquired {
+		_ = lock.Unlock(context.Background())
+		t.Errorf("Блокировка лидера не должна достаться другому участнику")
+	}
+
+	// обрыв соединения: лидерство теряется, ctx лидера отменяется, потом блокировка берётся снова
+	server.kill()
+	waitFor(t, revoked, "потери лидерства")
+	if leaderCtx.Err() == nil {
+		t.Errorf("При потере лидерства ctx из OnElected должен отменяться")
+	}
+	waitFor(t, elected, "повторного получения лидерства")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Ожидалась context.Canceled, а получили %v", err)
+	}
+	waitFor(t, revoked, "остановки Run")
+	if e.IsLeader() || server.holder("jobs.cleanup") != nil {
+		t.Errorf("После остановки Run блокировка должна быть отпущена")
+	}
+}
+
+func TestLeaderWaitsOnElected(t *testing.T) {
+	d, server := openLockDB(t)
+	stopping := make(chan struct{})
+	release := make(chan struct{})
+	revoked := make(chan struct{}, 1)
+	elected := make(chan struct{}, 1)
+	e := NewLeaderElection(d, "jobs.slow", LeaderConfig{
+		RetryInterval: time.Millisecond,
+		CheckInterval: time.Millisecond,
+		OnElected: func(ctx context.Context) {
+			elected <- struct{}{}
+			<-ctx.Done()
+			close(stopping)
+			<-release
+		},
+		OnRevoked: func() { revoked <- struct{}{} },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Run(ctx) }()
+	waitFor(t, elected, "получения лидерства")
+
+	cancel()
+	waitFor(t, stopping, "отмены ctx лидера")
+	select {
+	case <-revoked:
+		t.Errorf("OnRevoked не должен вызываться, пока работает OnElected")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if server.holder("jobs.slow") == nil {
+		t.Errorf("Блокировка должна держаться, пока работает OnElected")
+	}
+
+	close(release)
+	waitFor(t, revoked, "потери лидерства")
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Ожидалась context.Canceled, а получили %v", err)
+	}
+	if server.holder("jobs.slow") != nil {
+		t.Errorf("После возврата OnElected блокировка должна быть отпущена")
+	}
+}
+
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatalf("Не дождались %s", what)
+		var zero T
+		return zero
+	}
+}
+
+func TestMigratorSingleConnection(t *testing.T) {
+	d, server := openLockDB(t)
+	d.DB.SetMaxOpenConns(1)
+	fsys := fstest.MapFS{
+		"0001_create_users.up.sql": {Data: []byte("CREATE TABLE users ();")},
+	}
+	m, err := NewMigrator(d, fsys, MigratorConfig{})
+	if err != nil {
+		t.Fatalf("NewMigrator вернул ошибку: %v", err)
+	}
+
+	// с одним соединением в пуле миграции должны идти на соединении с блокировкой
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up вернул ошибку: %v", err)
+	}
+	if len(server.scripts) != 1 || server.holder("btools.migrate."+DefaultMigrationsTable) != nil {
+		t.Errorf("Ожидалась одна применённая миграция и отпущенная блокировка, а получили %v", server.scripts)
+	}
+}
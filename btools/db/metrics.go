@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets - границы бакетов гистограммы длительности запросов в секундах.
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64 // counts[i] - число наблюдений в бакете i (не кумулятивно), последний - +Inf
+	sum    float64
+	count  uint64
+}
+
+type metricKey struct {
+	kind   QueryKind
+	status string
+}
+
+// MetricsHook собирает гистограммы длительности запросов и счётчики ошибок в стиле Prometheus.
+// Метрики отдаются в текстовом формате экспозиции через WritePrometheus или как http.Handler.
+type MetricsHook struct {
+	Namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	durations map[metricKey]*histogram
+	rows      map[QueryKind]int64
+	errors    map[string]uint64
+}
+
+func NewMetricsHook(namespace string, buckets []float64) *MetricsHook {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	if namespace == "" {
+		namespace = "db"
+	}
+	return &MetricsHook{
+		Namespace: namespace,
+		buckets:   sorted,
+		durations: make(map[metricKey]*histogram),
+		rows:      make(map[QueryKind]int64),
+		errors:    make(map[string]uint64),
+	}
+}
+
+func (m *MetricsHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (m *MetricsHook) AfterQuery(_ context.Context, event *QueryEvent) {
+	status := "ok"
+	if event.Err != nil {
+		status = "error"
+	}
+	seconds := event.Duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := metricKey{kind: event.Kind, status: status}
+	h, ok := m.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.durations[key] = h
+	}
+	idx := sort.SearchFloat64s(m.buckets, seconds)
+	h.counts[idx]++
+	h.sum += seconds
+	h.count++
+
+	if event.RowsAffected > 0 {
+		m.rows[event.Kind] += event.RowsAffected
+	}
+	if event.Err != nil {
+		code := event.ErrorCode
+		if code == "" {
+			code = "unknown"
+		}
+		m.errors[code]++
+	}
+}
+
+// WritePrometheus пишет метрики в текстовом формате Prometheus.
+func (m *MetricsHook) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	name := m.Namespace + "_query_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Duration of SQL queries, execs and transactions.\n", name)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+
+	keys := make([]metricKey, 0, len(m.durations))
+	for k := range m.durations {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		h := m.durations[k]
+		labels := fmt.Sprintf(`kind="%s",status="%s"`, k.kind, k.status)
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+
+	rowsName := m.Namespace + "_rows_affected_total"
+	fmt.Fprintf(&b, "# HELP %s Rows returned or affected by SQL statements.\n", rowsName)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", rowsName)
+	kinds := make([]string, 0, len(m.rows))
+	for k := range m.rows {
+		kinds = append(kinds, string(k))
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		fmt.Fprintf(&b, "%s{kind=\"%s\"} %d\n", rowsName, k, m.rows[QueryKind(k)])
+	}
+
+	errorsName := m.Namespace + "_errors_total"
+	fmt.Fprintf(&b, "# HELP %s SQL errors by SQLSTATE code.\n", errorsName)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", errorsName)
+	codes := make([]string, 0, len(m.errors))
+	for c := range m.errors {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	for _, c := range codes {
+		fmt.Fprintf(&b, "%s{code=\"%s\"} %d\n", errorsName, c, m.errors[c])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP позволяет повесить MetricsHook на /metrics.
+func (m *MetricsHook) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.WritePrometheus(w); err != nil {
+		log.Error(err, "failed to write db metrics")
+	}
+}
@@ -0,0 +1,353 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var migrateLogger = log.NewSubLogger("migrate")
+
+// DefaultMigrationsTable - таблица, в которой хранятся применённые миграции.
+const DefaultMigrationsTable = "schema_migrations"
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration описывает одну версию схемы: пару up/down скриптов.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 от up-скрипта
+}
+
+// MigrationStatus - состояние миграции относительно базы.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool // up-скрипт изменился после применения
+}
+
+// MigratorConfig - настройки мигратора.
+type MigratorConfig struct {
+	Dir   string // каталог с миграциями внутри fs.FS; по умолчанию корень
+	Table string // по умолчанию DefaultMigrationsTable
+}
+
+// Migrator применяет версионированные SQL-миграции из fs.FS (удобно вместе с go:embed).
+// Файлы именуются как 0001_create_users.up.sql / 0001_create_users.down.sql.
+type Migrator struct {
+	conn       *Database
+	table      string
+	migrations []*Migration
+}
+
+func NewMigrator(conn *Database, fsys fs.FS, cfg MigratorConfig) (*Migrator, error) {
+	if cfg.Table == "" {
+		cfg.Table = DefaultMigrationsTable
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "."
+	}
+	migrations, err := LoadMigrations(fsys, cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		conn:       conn,
+		table:      cfg.Table,
+		migrations: migrations,
+	}, nil
+}
+
+// LoadMigrations читает и валидирует миграции из каталога dir.
+func LoadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		switch match[3] {
+		case "up":
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		case "down":
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrations возвращает все найденные миграции, отсортированные по версии.
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// Up применяет все ещё не применённые миграции.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down откатывает последнюю применённую миграцию.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok {
+				return m.revert(ctx, conn, migration)
+			}
+		}
+		migrateLogger.Info("no migrations to revert")
+		return nil
+	})
+}
+
+// To приводит схему к указанной версии: применяет миграции до version включительно
+// и откатывает всё, что новее. To(ctx, 0) откатывает все миграции.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("migration %d not found", version)
+	}
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.revert(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status возвращает состояние всех известных миграций.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.conn.DB.Connx(ctx)
+	if err != nil {
+		return nil, WrapError(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.Checksum != migration.Checksum
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// withLock выполняет fn под advisory lock, чтобы несколько экземпляров сервиса
+// не накатывали миграции одновременно. Миграции идут на том же соединении, что держит
+// блокировку: второе соединение из пула при SetMaxOpenConns(1) ждало бы вечно.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	lock, err := m.conn.Lock(ctx, "btools.migrate."+m.table)
+	if err != nil {
+		migrateLogger.Error(err, "failed to acquire migration lock")
+		return err
+	}
+	defer func() {
+		if err := lock.Unlock(context.Background()); err != nil {
+			migrateLogger.Error(err, "failed to release migration lock")
+		}
+	}()
+
+	if err := m.ensureTable(ctx, lock.conn); err != nil {
+		return err
+	}
+	return fn(lock.conn)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sqlx.Conn) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	checksum   TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, m.table)
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		migrateLogger.Error(err, "failed to create migrations table %s", m.table)
+		return WrapError(err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sqlx.Conn) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	query := fmt.Sprintf("SELECT version, checksum, applied_at FROM %s", m.table)
+	if err := conn.SelectContext(ctx, &rows, query); err != nil {
+		return nil, WrapError(err)
+	}
+	result := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, migration := range m.migrations {
+		row, ok := applied[migration.Version]
+		if ok && row.Checksum != migration.Checksum {
+			return fmt.Errorf("migration %d_%s was modified after being applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, migration *Migration) error {
+	migrateLogger.Info("applying migration %d_%s", migration.Version, migration.Name)
+	return m.inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		query := fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)", m.table)
+		_, err := tx.ExecContext(ctx, query, migration.Version, migration.Name, migration.Checksum)
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sqlx.Conn, migration *Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+	}
+	migrateLogger.Info("reverting migration %d_%s", migration.Version, migration.Name)
+	return m.inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.table)
+		_, err := tx.ExecContext(ctx, query, migration.Version)
+		return err
+	})
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return WrapError(err)
+	}
+	if err := fn(tx); err != nil {
+		migrateLogger.Error(err, "migration failed. Rollback...")
+		_ = tx.Rollback()
+		return WrapError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return WrapError(err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// ErrUnknownMigrateCommand возвращается RunMigrateCommand для неизвестной команды.
+var ErrUnknownMigrateCommand = errors.New("unknown migrate command")
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down          revert the last applied migration
+  to <version>  migrate up or down to the given version
+  status        print migrations status
+`
+
+// RunMigrateCommand - минимальный CLI поверх Migrator. Его удобно встраивать в бинарь
+// сервиса (например, как подкоманду `migrate`), чтобы использовать go:embed миграции.
+func RunMigrateCommand(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(out, migrateUsage)
+		return ErrUnknownMigrateCommand
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("migrate to: version is required")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate to: invalid version %q: %w", args[1], err)
+		}
+		return m.To(ctx, version)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(out, statuses)
+	default:
+		_, _ = fmt.Fprint(out, migrateUsage)
+		return fmt.Errorf("%w: %s", ErrUnknownMigrateCommand, args[0])
+	}
+}
+
+func printMigrationStatus(out io.Writer, statuses []MigrationStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		appliedAt := "-"
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Modified {
+			state += " (modified)"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
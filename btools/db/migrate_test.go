@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"migrations/0002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id BIGSERIAL PRIMARY KEY);")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/README.md":                  {Data: []byte("не миграция")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("LoadMigrations вернула ошибку: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Ожидалось 2 миграции, а получили %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_users" {
+		t.Errorf("Ожидалась первой миграция 1_create_users, а получили %d_%s", migrations[0].Version, migrations[0].Name)
+	}
+	if migrations[1].Version != 2 || migrations[1].Down == "" {
+		t.Errorf("Ожидалась миграция 2 с down-скриптом, а получили %+v", migrations[1])
+	}
+	if migrations[0].Checksum == "" || migrations[0].Checksum == migrations[1].Checksum {
+		t.Errorf("Ожидались разные непустые контрольные суммы")
+	}
+}
+
+func TestLoadMigrations_MissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+	if _, err := LoadMigrations(fsys, "."); err == nil {
+		t.Error("Ожидалась ошибка для миграции без up-скрипта")
+	}
+}
+
+func TestLoadMigrations_ConflictingNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_users.up.sql":  {Data: []byte("CREATE TABLE users ();")},
+		"0001_create_orders.up.sql": {Data: []byte("CREATE TABLE orders ();")},
+	}
+	if _, err := LoadMigrations(fsys, "."); err == nil {
+		t.Error("Ожидалась ошибка для двух миграций с одной версией")
+	}
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_users.up.sql":    {Data: []byte("CREATE TABLE users ();")},
+		"0001_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"0002_create_orders.up.sql":   {Data: []byte("CREATE TABLE orders ();")},
+		"0002_create_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+		"0003_create_items.up.sql":    {Data: []byte("CREATE TABLE items ();")},
+		"0003_create_items.down.sql":  {Data: []byte("DROP TABLE items;")},
+	}
+}
+
+// takeScripts возвращает выполненные скрипты миграций и очищает список
+func (s *lockServer) takeScripts() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scripts := strings.Join(s.scripts, " ")
+	s.scripts = nil
+	return scripts
+}
+
+func TestMigratorUpDownTo(t *testing.T) {
+	d, server := openLockDB(t)
+	m, err := NewMigrator(d, testMigrations(), MigratorConfig{})
+	if err != nil {
+		t.Fatalf("NewMigrator вернул ошибку: %v", err)
+	}
+	ctx := context.Background()
+
+	steps := []struct {
+		name     string
+		run      func() error
+		expected string
+	}{
+		{"Up", func() error { return m.Up(ctx) },
+			"CREATE TABLE users (); CREATE TABLE orders (); CREATE TABLE items ();"},
+		{"повторный Up", func() error { return m.Up(ctx) }, ""},
+		{"Down", func() error { return m.Down(ctx) }, "DROP TABLE items;"},
+		{"To(1)", func() error { return m.To(ctx, 1) }, "DROP TABLE orders;"},
+		{"To(3)", func() error { return m.To(ctx, 3) }, "CREATE TABLE orders (); CREATE TABLE items ();"},
+		{"To(0)", func() error { return m.To(ctx, 0) }, "DROP TABLE items; DROP TABLE orders; DROP TABLE users;"},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s вернул ошибку: %v", step.name, err)
+		}
+		if got := server.takeScripts(); got != step.expected {
+			t.Errorf("%s: ожидалось %q, а выполнено %q", step.name, step.expected, got)
+		}
+	}
+
+	if err := m.To(ctx, 5); err == nil {
+		t.Errorf("Ожидалась ошибка для неизвестной версии")
+	}
+}
+
+func TestMigratorChecksumMismatch(t *testing.T) {
+	d, server := openLockDB(t)
+	ctx := context.Background()
+	m, err := NewMigrator(d, testMigrations(), MigratorConfig{})
+	if err != nil {
+		t.Fatalf("NewMigrator вернул ошибку: %v", err)
+	}
+	if err := m.To(ctx, 2); err != nil {
+		t.Fatalf("To вернул ошибку: %v", err)
+	}
+	server.takeScripts()
+
+	// up-скрипт уже применённой миграции изменили
+	fsys := testMigrations()
+	fsys["0001_create_users.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id BIGINT);")}
+	m, err = NewMigrator(d, fsys, MigratorConfig{})
+	if err != nil {
+		t.Fatalf("NewMigrator вернул ошибку: %v", err)
+	}
+	if err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "1_create_users was modified") {
+		t.Errorf("Up должен отказываться работать при изменённой миграции, а вернул %v", err)
+	}
+	if err := m.To(ctx, 3); err == nil {
+		t.Errorf("To должен отказываться работать при изменённой миграции")
+	}
+	if scripts := server.takeScripts(); scripts != "" {
+		t.Errorf("При несовпадении контрольной суммы ничего не должно выполняться, а выполнено %q", scripts)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status вернул ошибку: %v", err)
+	}
+	if len(status) != 3 || !status[0].Modified || status[1].Modified || status[2].Applied {
+		t.Errorf("Неверный статус миграций: %+v", status)
+	}
+}
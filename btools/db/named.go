@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/seemyown/backend-toolkit/btools/exc"
+)
+
+// Execer - общий интерфейс для записи, который реализуют *sqlx.DB, *sqlx.Tx и *Database.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Rebind(query string) string
+}
+
+// BindNamed подставляет параметры :name из arg (структура с тегами `db` или map[string]interface{}),
+// разворачивает слайсы в IN (...) как SelectIn и переводит плейсхолдеры под драйвер через rebind.
+//
+//	q, args, err := BindNamed(db.Rebind, "SELECT * FROM users WHERE status = :status AND id IN (:ids)",
+//		map[string]interface{}{"status": "active", "ids": []int64{1, 2, 3}})
+func BindNamed(rebind func(string) string, query string, arg interface{}) (string, []interface{}, error) {
+	q, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return "", nil, err
+	}
+	q, args, err = sqlx.In(q, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	return rebind(q), args, nil
+}
+
+func SelectOneNamed[T any](db Querier, ctx context.Context, query string, arg interface{}) (*T, error) {
+	q, args, err := BindNamed(db.Rebind, query, arg)
+	if err != nil {
+		return nil, exc.RepositoryError(err.Error())
+	}
+	return SelectOne[T](db, ctx, q, args...)
+}
+
+func SelectManyNamed[T any](db Querier, ctx context.Context, query string, arg interface{}) ([]*T, error) {
+	q, args, err := BindNamed(db.Rebind, query, arg)
+	if err != nil {
+		return nil, exc.RepositoryError(err.Error())
+	}
+	return SelectMany[T](db, ctx, q, args...)
+}
+
+func ExecNamed(ctx context.Context, db Execer, query string, arg interface{}) (sql.Result, error) {
+	q, args, err := BindNamed(db.Rebind, query, arg)
+	if err != nil {
+		return nil, exc.RepositoryError(err.Error())
+	}
+	result, err := instrument(db).ExecContext(ctx, q, args...)
+	if err != nil {
+		Logger.Error(err, "failed to execute query %s, %v", q, args)
+		return nil, WrapError(err)
+	}
+	return result, nil
+}
+
+func (r *BaseRepository[T, K]) SelectOneNamed(ctx context.Context, query string, arg interface{}) (*T, error) {
+	return SelectOneNamed[T](r.Reader(), ctx, query, arg)
+}
+
+func (r *BaseRepository[T, K]) SelectManyNamed(ctx context.Context, query string, arg interface{}) ([]*T, error) {
+	return SelectManyNamed[T](r.Reader(), ctx, query, arg)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func dollar(query string) string {
+	return sqlx.Rebind(sqlx.DOLLAR, query)
+}
+
+type userFilter struct {
+	Status string  `db:"status"`
+	IDs    []int64 `db:"ids"`
+	Limit  int     `db:"limit"`
+}
+
+func TestBindNamed(t *testing.T) {
+	query := "SELECT * FROM users WHERE status = :status AND id IN (:ids) LIMIT :limit"
+
+	q, args, err := BindNamed(dollar, query, userFilter{Status: "active", IDs: []int64{1, 2, 3}, Limit: 10})
+	if err != nil {
+		t.Fatalf("BindNamed вернула ошибку: %v", err)
+	}
+	if q != "SELECT * FROM users WHERE status = $1 AND id IN ($2, $3, $4) LIMIT $5" {
+		t.Errorf("Неверный запрос: %s", q)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"active", int64(1), int64(2), int64(3), 10}) {
+		t.Errorf("Неверные аргументы: %v", args)
+	}
+
+	q, args, err = BindNamed(dollar, "UPDATE users SET name = :name WHERE id = :id", map[string]interface{}{
+		"id":   int64(7),
+		"name": "Alice",
+	})
+	if err != nil {
+		t.Fatalf("BindNamed вернула ошибку: %v", err)
+	}
+	if q != "UPDATE users SET name = $1 WHERE id = $2" || !reflect.DeepEqual(args, []interface{}{"Alice", int64(7)}) {
+		t.Errorf("Неверная привязка из map: %s %v", q, args)
+	}
+}
+
+func TestBindNamed_Errors(t *testing.T) {
+	if _, _, err := BindNamed(dollar, "SELECT * FROM users WHERE id = :id", map[string]interface{}{}); err == nil {
+		t.Errorf("Ожидалась ошибка для отсутствующего параметра")
+	}
+	if _, _, err := BindNamed(dollar, "SELECT * FROM users WHERE id IN (:ids)", userFilter{}); err == nil {
+		t.Errorf("Ожидалась ошибка для пустого слайса в IN")
+	}
+}
+
+func TestExecNamed(t *testing.T) {
+	q := &recordingQuerier{rows: 2}
+	result, err := ExecNamed(context.Background(), q, "DELETE FROM users WHERE id IN (:ids)", userFilter{IDs: []int64{4, 5}})
+	if err != nil {
+		t.Fatalf("ExecNamed вернула ошибку: %v", err)
+	}
+	if q.query != "DELETE FROM users WHERE id IN (?, ?)" || len(q.args) != 2 {
+		t.Errorf("Неверный запрос: %s %v", q.query, q.args)
+	}
+	if rows, _ := result.RowsAffected(); rows != 2 {
+		t.Errorf("Ожидалось 2 затронутые строки, а получили %d", rows)
+	}
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var replicaLogger = log.NewSubLogger("replica")
+
+const defaultReplicaHealthCheckInterval = 5 * time.Second
+
+type replica struct {
+	host    string
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+type primaryCtxKey struct{}
+
+// WithPrimary помечает контекст так, что все чтения через Database идут на основной сервер.
+// Используется для чтения сразу после записи, когда реплики могут отставать.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// UsesPrimary сообщает, помечен ли контекст через WithPrimary.
+func UsesPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return v
+}
+
+func (d *Database) connectReplicas(cfg *Config) {
+	if len(cfg.Replicas) == 0 {
+		return
+	}
+
+	interval := cfg.ReplicaHealthCheckInterval
+	if interval <= 0 {
+		interval = defaultReplicaHealthCheckInterval
+	}
+
+	for _, host := range cfg.Replicas {
+		replicaCfg := cfg.replica(host)
+		conn, err := sqlx.Open(replicaCfg.driverName(), replicaCfg.String())
+		if err != nil {
+			replicaLogger.Error(err, "invalid replica config %s", host)
+			continue
+		}
+		r := &replica{host: host, db: conn}
+		databases.Store(conn, d)
+		// недоступная реплика не должна держать старт сервиса дольше одной проверки
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := conn.PingContext(ctx); err != nil {
+			replicaLogger.Error(err, "replica %s is unavailable", host)
+		} else {
+			r.healthy.Store(true)
+		}
+		cancel()
+		d.replicas = append(d.replicas, r)
+	}
+
+	d.stopCheck = make(chan struct{})
+	go d.checkReplicas(interval)
+}
+
+func (d *Database) checkReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopCheck:
+			return
+		case <-ticker.C:
+			for _, r := range d.replicas {
+				r.check(interval)
+			}
+		}
+	}
+}
+
+func (r *replica) check(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		replicaLogger.Info("replica %s is back online", r.host)
+	} else {
+		replicaLogger.Error(err, "replica %s is unavailable, reads go to other nodes", r.host)
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func openLazy(t *testing.T, host string) *sqlx.DB {
+	// sqlx.Open не устанавливает соединение, поэтому сервер для теста не нужен
+	conn, err := sqlx.Open("postgres", (&Config{Host: host, Port: "5432"}).String())
+	if err != nil {
+		t.Fatalf("Не удалось открыть подключение: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestConfigReplica(t *testing.T) {
+	cfg := &Config{Host: "primary", Port: "5432", Username: "app", Replicas: []string{"r1:6432"}}
+
+	withPort := cfg.replica("r1:6432")
+	if withPort.Host != "r1" || withPort.Port != "6432" || withPort.Username != "app" {
+		t.Errorf("Неверный конфиг реплики: %+v", withPort)
+	}
+	if withPort.Replicas != nil {
+		t.Errorf("У конфига реплики не должно быть своих реплик")
+	}
+
+	hostOnly := cfg.replica("r2")
+	if hostOnly.Host != "r2" || hostOnly.Port != "5432" {
+		t.Errorf("Ожидался хост r2 с портом основного сервера, а получили %s:%s", hostOnly.Host, hostOnly.Port)
+	}
+}
+
+func TestDatabaseReader(t *testing.T) {
+	primary := openLazy(t, "primary")
+	healthy := &replica{host: "r1", db: openLazy(t, "r1")}
+	healthy.healthy.Store(true)
+	broken := &replica{host: "r2", db: openLazy(t, "r2")}
+
+	d := &Database{DB: primary, replicas: []*replica{healthy, broken}}
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if got := d.Reader(ctx); got != healthy.db {
+			t.Fatalf("Ожидалось чтение со здоровой реплики")
+		}
+	}
+	if got := d.Reader(WithPrimary(ctx)); got != primary {
+		t.Errorf("WithPrimary должен направлять чтение на основной сервер")
+	}
+
+	healthy.healthy.Store(false)
+	if got := d.Reader(ctx); got != primary {
+		t.Errorf("Без здоровых реплик чтение должно идти на основной сервер")
+	}
+
+	if got := (&Database{DB: primary}).Reader(ctx); got != primary {
+		t.Errorf("Без реплик чтение должно идти на основной сервер")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/seemyown/backend-toolkit/btools/exc"
 	"github.com/seemyown/backend-toolkit/btools/logging"
+	"reflect"
 )
 
 var Logger = logging.New(logging.Config{
@@ -12,81 +13,173 @@ var Logger = logging.New(logging.Config{
 	Name:     "base",
 })
 
-type Repository[T any] interface {
+// Repository - репозиторий сущности T с первичным ключом K. K может быть скаляром
+// (int64, string, uuid.UUID) или структурой с тегами `db` для составного ключа.
+type Repository[T any, K comparable] interface {
 	Create(ctx context.Context, entity *T) error
 	CreateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error
-	Get(ctx context.Context, id int64) (*T, error)
+	Get(ctx context.Context, id K) (*T, error)
 	Update(ctx context.Context, entity *T) error
 	UpdateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error
-	Delete(ctx context.Context, id int64) error
-	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	Delete(ctx context.Context, id K) error
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id K) error
 	GetAll(ctx context.Context, args ...interface{}) ([]*T, error)
 	Search(ctx context.Context, args ...interface{}) ([]*T, error)
 }
 
-type BaseRepository[T any] struct {
-	Db  *sqlx.DB
-	Trx Transaction
+// Int64Repository - репозиторий с ключом int64, как до появления параметра K.
+type Int64Repository[T any] = Repository[T, int64]
+
+// Int64BaseRepository - BaseRepository с ключом int64, его возвращает NewBaseRepository.
+type Int64BaseRepository[T any] = BaseRepository[T, int64]
+
+type BaseRepository[T any, K comparable] struct {
+	Db   *sqlx.DB
+	Trx  Transaction
+	Conn *Database
+
+	meta *tableMeta
 }
 
-func (r *BaseRepository[T]) Create(ctx context.Context, entity *T) error {
-	return exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) Create(ctx context.Context, entity *T) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.insert(WithPrimary(ctx), r.writer(), entity)
 }
 
-func (r *BaseRepository[T]) CreateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error {
-	return exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) CreateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.insert(ctx, tx, entity)
 }
 
-func (r *BaseRepository[T]) Get(ctx context.Context, id int64) (*T, error) {
-	return nil, exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) Get(ctx context.Context, id K) (*T, error) {
+	if r.meta == nil {
+		return nil, exc.RepositoryError("Not implemented")
+	}
+	var result T
+	if err := r.meta.get(ctx, r.Reader(), &result, r.meta.keyArgs(id)); err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
 
-func (r *BaseRepository[T]) Update(ctx context.Context, entity *T) error {
-	return exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) Update(ctx context.Context, entity *T) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.update(WithPrimary(ctx), r.writer(), entity)
 }
 
-func (r *BaseRepository[T]) Delete(ctx context.Context, id int64) error {
-	return exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) Delete(ctx context.Context, id K) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.delete(ctx, r.writer(), r.meta.keyArgs(id), false)
 }
-func (r *BaseRepository[T]) UpdateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error {
-	return exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) UpdateTx(ctx context.Context, tx *sqlx.Tx, entity *T) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.update(ctx, tx, entity)
 }
 
-func (r *BaseRepository[T]) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
-	return exc.RepositoryError("Not implemented")
+func (r *BaseRepository[T, K]) DeleteTx(ctx context.Context, tx *sqlx.Tx, id K) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.delete(ctx, tx, r.meta.keyArgs(id), false)
 }
 
-func (r *BaseRepository[T]) GetAll(ctx context.Context, args ...interface{}) ([]*T, error) {
-	return make([]*T, 0), exc.RepositoryError("Not implemented")
+// HardDelete удаляет строку физически, даже если включён WithSoftDelete.
+func (r *BaseRepository[T, K]) HardDelete(ctx context.Context, id K) error {
+	if r.meta == nil {
+		return exc.RepositoryError("Not implemented")
+	}
+	return r.meta.delete(ctx, r.writer(), r.meta.keyArgs(id), true)
 }
 
-func (r *BaseRepository[T]) Search(ctx context.Context, args ...interface{}) ([]*T, error) {
+func (r *BaseRepository[T, K]) GetAll(ctx context.Context, args ...interface{}) ([]*T, error) {
+	if r.meta == nil {
+		return make([]*T, 0), exc.RepositoryError("Not implemented")
+	}
+	result := make([]*T, 0)
+	if err := r.meta.getAll(ctx, r.Reader(), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *BaseRepository[T, K]) Search(ctx context.Context, args ...interface{}) ([]*T, error) {
 	return make([]*T, 0), exc.RepositoryError("Not implemented")
 }
 
-func (r *BaseRepository[T]) WithTrx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+func (r *BaseRepository[T, K]) WithTrx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
 	return r.Trx.Exec(ctx, fn)
 }
 
-func NewBaseRepository[T any](conn *Database) *BaseRepository[T] {
-	return &BaseRepository[T]{
-		Db:  conn.DB,
-		Trx: NewTrx(conn),
+// NewBaseRepository создаёт репозиторий с ключом int64. Без опций методы CRUD возвращают
+// "Not implemented" и должны быть переопределены; с WithTable работает встроенная реализация.
+func NewBaseRepository[T any](conn *Database, opts ...RepositoryOption) *BaseRepository[T, int64] {
+	return NewRepository[T, int64](conn, opts...)
+}
+
+// NewRepository создаёт репозиторий с ключом типа K, см. NewBaseRepository.
+func NewRepository[T any, K comparable](conn *Database, opts ...RepositoryOption) *BaseRepository[T, K] {
+	repo := &BaseRepository[T, K]{
+		Db:   conn.DB,
+		Trx:  NewTrx(conn),
+		Conn: conn,
+	}
+
+	var options repositoryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.table != "" {
+		meta, err := newTableMeta(reflect.TypeOf((*T)(nil)).Elem(), options)
+		if err == nil {
+			err = meta.bindKey(reflect.TypeOf((*K)(nil)).Elem())
+		}
+		if err != nil {
+			Logger.Error(err, "invalid repository configuration")
+			panic(err)
+		}
+		repo.meta = meta
+	}
+	return repo
+}
+
+// Reader возвращает источник для чтения: реплику, если репозиторий создан через
+// NewBaseRepository, иначе Db.
+func (r *BaseRepository[T, K]) Reader() Querier {
+	if r.Conn != nil {
+		return r.Conn
+	}
+	return r.Db
+}
+
+func (r *BaseRepository[T, K]) writer() execQuerier {
+	if r.Conn != nil {
+		return r.Conn
 	}
+	return r.Db
 }
 
-func (r *BaseRepository[T]) SelectOne(ctx context.Context, query string, args ...interface{}) (*T, error) {
+func (r *BaseRepository[T, K]) SelectOne(ctx context.Context, query string, args ...interface{}) (*T, error) {
 	var result T
-	if err := r.Db.GetContext(ctx, &result, query, args...); err != nil {
+	if err := r.Reader().GetContext(ctx, &result, query, args...); err != nil {
 		Logger.Error(err, "failed to execute query %s, %v", query, args)
 		return nil, WrapError(err)
 	}
 	return &result, nil
 }
 
-func (r *BaseRepository[T]) SelectMany(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
+func (r *BaseRepository[T, K]) SelectMany(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
 	var result []*T
-	if err := r.Db.SelectContext(ctx, &result, query, args...); err != nil {
+	if err := r.Reader().SelectContext(ctx, &result, query, args...); err != nil {
 		Logger.Error(err, "failed to execute query %s, %v", query, args)
 		return nil, WrapError(err)
 	}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultCursorBatchSize - сколько строк StreamCursor забирает за один FETCH по умолчанию.
+const DefaultCursorBatchSize = 1000
+
+// RowsQuerier - источник построчного чтения: *sqlx.DB, *sqlx.Tx, *sqlx.Conn и *Database.
+type RowsQuerier interface {
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+}
+
+// QueryxContext открывает построчное чтение на реплике (см. Reader). Rows нужно закрыть.
+// Если в контексте есть арендатор, возвращает ErrTenantStreaming.
+func (d *Database) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if name, _, err := d.tenancy.tenantSettings(ctx); err != nil || name != "" {
+		if err == nil {
+			err = ErrTenantStreaming
+		}
+		return nil, err
+	}
+	var rows *sqlx.Rows
+	err := d.observe(ctx, QueryKindQuery, query, args, func(ctx context.Context) (int64, error) {
+		var err error
+		rows, err = d.Reader(ctx).QueryxContext(ctx, query, args...)
+		return -1, err
+	})
+	return rows, err
+}
+
+// Stream читает результат запроса по одной строке, не загружая его в память целиком, как SelectMany.
+// Итерацию можно прервать break-ом - rows закроются. Ошибка запроса, сканирования или отмены ctx
+// приходит последним элементом с nil-значением.
+//
+//	for user, err := range db.Stream[User](conn, ctx, "SELECT * FROM users") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Stream[T any](db RowsQuerier, ctx context.Context, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		rows, err := db.QueryxContext(ctx, query, args...)
+		if err != nil {
+			Logger.Error(err, "failed to execute query %s, %v", query, args)
+			yield(nil, WrapError(err))
+			return
+		}
+		defer func() { _ = rows.Close() }()
+
+		if _, err := yieldRows[T](ctx, rows, yield); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+var cursorSeq atomic.Uint64
+
+// StreamCursor читает результат через серверный курсор порциями по batchSize строк
+// (DefaultCursorBatchSize, если batchSize <= 0). В отличие от Stream, драйвер не держит в памяти
+// весь результат, поэтому подходит для очень больших выгрузок. Курсор живёт только внутри tx.
+func StreamCursor[T any](tx *sqlx.Tx, ctx context.Context, batchSize int, query string, args ...interface{}) iter.Seq2[*T, error] {
+	if batchSize <= 0 {
+		batchSize = DefaultCursorBatchSize
+	}
+	return func(yield func(*T, error) bool) {
+		name := fmt.Sprintf("btools_cursor_%d", cursorSeq.Add(1))
+		if _, err := tx.ExecContext(ctx, "DECLARE "+name+" NO SCROLL CURSOR FOR "+query, args...); err != nil {
+			Logger.Error(err, "failed to declare cursor for query %s, %v", query, args)
+			yield(nil, WrapError(err))
+			return
+		}
+		defer func() {
+			// после ошибки транзакция уже прервана, и CLOSE тоже упадёт - курсор закроется вместе с ней
+			_, _ = tx.ExecContext(context.WithoutCancel(ctx), "CLOSE "+name)
+		}()
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", batchSize, name)
+		for {
+			rows, err := tx.QueryxContext(ctx, fetch)
+			if err != nil {
+				yield(nil, WrapError(err))
+				return
+			}
+			n, err := yieldRows[T](ctx, rows, yield)
+			_ = rows.Close()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if n < batchSize {
+				return
+			}
+		}
+	}
+}
+
+// yieldRows отдаёт строки rows в yield. Возвращает число строк, -1 если итерацию прервали.
+func yieldRows[T any](ctx context.Context, rows *sqlx.Rows, yield func(*T, error) bool) (int, error) {
+	scan := scanFunc[T](rows)
+	n := 0
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		var item T
+		if err := scan(&item); err != nil {
+			return n, WrapError(err)
+		}
+		n++
+		if !yield(&item, nil) {
+			return -1, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, WrapError(err)
+	}
+	return n, nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// scanFunc выбирает способ сканирования так же, как sqlx.Get: структуры - по колонкам,
+// скаляры, sql.Scanner и структуры без экспортируемых полей (time.Time) - напрямую.
+func scanFunc[T any](rows *sqlx.Rows) func(*T) error {
+	if isScannable(reflect.TypeOf((*T)(nil)).Elem()) {
+		return func(dest *T) error { return rows.Scan(dest) }
+	}
+	return func(dest *T) error { return rows.StructScan(dest) }
+}
+
+func isScannable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(scannerType) || t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
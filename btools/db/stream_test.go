@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// streamDriver - драйвер database/sql, который отдаёт фиксированную таблицу users
+// и понимает FETCH FORWARD n для курсоров
+type streamDriver struct {
+	mu      sync.Mutex
+	execs   []string
+	fetches int
+	closed  int
+}
+
+var testStreamDriver = &streamDriver{}
+
+func init() {
+	sql.Register("btools-stream", testStreamDriver)
+}
+
+func (d *streamDriver) Open(string) (driver.Conn, error) {
+	return &streamConn{driver: d}, nil
+}
+
+type streamConn struct {
+	driver *streamDriver
+	offset int
+}
+
+func (c *streamConn) Prepare(query string) (driver.Stmt, error) { return streamStmt{c, query}, nil }
+func (c *streamConn) Close() error                              { return nil }
+func (c *streamConn) Begin() (driver.Tx, error)                 { return streamTx{}, nil }
+
+func (c *streamConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.execs = append(c.driver.execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *streamConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	rows := &streamRows{driver: c.driver, columns: []string{"id", "name"}, from: 0, to: 5}
+	if strings.HasPrefix(query, "SELECT id FROM") {
+		rows.columns = []string{"id"}
+	}
+	var batch int
+	if _, err := fmt.Sscanf(query, "FETCH FORWARD %d", &batch); err == nil {
+		c.driver.mu.Lock()
+		c.driver.fetches++
+		c.driver.mu.Unlock()
+		rows.from, rows.to = c.offset, min(c.offset+batch, 5)
+		c.offset = rows.to
+	}
+	return rows, nil
+}
+
+// streamStmt поддерживает только Exec: его хватает для PrepareAndExec
+type streamStmt struct {
+	conn  *streamConn
+	query string
+}
+
+func (s streamStmt) Close() error  { return nil }
+func (s streamStmt) NumInput() int { return -1 }
+
+func (s streamStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s streamStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+type streamTx struct{}
+
+func (streamTx) Commit() error   { return nil }
+func (streamTx) Rollback() error { return nil }
+
+type streamRows struct {
+	driver   *streamDriver
+	columns  []string
+	from, to int
+}
+
+func (r *streamRows) Columns() []string { return r.columns }
+
+func (r *streamRows) Close() error {
+	r.driver.mu.Lock()
+	defer r.driver.mu.Unlock()
+	r.driver.closed++
+	return nil
+}
+
+func (r *streamRows) Next(dest []driver.Value) error {
+	if r.from >= r.to {
+		return io.EOF
+	}
+	r.from++
+	dest[0] = int64(r.from)
+	if len(dest) > 1 {
+		dest[1] = fmt.Sprintf("user%d", r.from)
+	}
+	return nil
+}
+
+func (d *streamDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execs, d.fetches, d.closed = nil, 0, 0
+}
+
+type streamUser struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func openStreamDB(t *testing.T) *sqlx.DB {
+	testStreamDriver.reset()
+	conn, err := sqlx.Open("btools-stream", "")
+	if err != nil {
+		t.Fatalf("Не удалось открыть тестовый драйвер: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestStream(t *testing.T) {
+	conn := openStreamDB(t)
+	ctx := context.Background()
+
+	var names []string
+	for user, err := range Stream[streamUser](conn, ctx, "SELECT id, name FROM users") {
+		if err != nil {
+			t.Fatalf("Stream вернул ошибку: %v", err)
+		}
+		names = append(names, user.Name)
+	}
+	if strings.Join(names, ",") != "user1,user2,user3,user4,user5" {
+		t.Errorf("Ожидались все пять строк, а получили %v", names)
+	}
+
+	var ids []int64
+	for id, err := range Stream[int64](conn, ctx, "SELECT id FROM users") {
+		if err != nil {
+			t.Fatalf("Stream вернул ошибку: %v", err)
+		}
+		ids = append(ids, *id)
+		if len(ids) == 2 {
+			break
+		}
+	}
+	if len(ids) != 2 || ids[1] != 2 {
+		t.Errorf("Ожидались первые два id, а получили %v", ids)
+	}
+	if testStreamDriver.closed != 2 {
+		t.Errorf("Rows должны закрываться и при полном проходе, и при break, а закрыто %d", testStreamDriver.closed)
+	}
+}
+
+func TestStream_Cancelled(t *testing.T) {
+	conn := openStreamDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lastErr error
+	count := 0
+	for _, err := range Stream[streamUser](conn, ctx, "SELECT id, name FROM users") {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		count++
+		cancel()
+	}
+	if count != 1 || !errors.Is(lastErr, context.Canceled) {
+		t.Errorf("После отмены контекста ожидалась ошибка context.Canceled, а получили %d строк и %v", count, lastErr)
+	}
+}
+
+func TestStreamCursor(t *testing.T) {
+	conn := openStreamDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		t.Fatalf("Beginx вернул ошибку: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	count := 0
+	for _, err := range StreamCursor[streamUser](tx, ctx, 2, "SELECT id, name FROM users WHERE id > $1", 0) {
+		if err != nil {
+			t.Fatalf("StreamCursor вернул ошибку: %v", err)
+		}
+		count++
+	}
+	if count != 5 {
+		t.Errorf("Ожидалось 5 строк, а получили %d", count)
+	}
+	if testStreamDriver.fetches != 3 {
+		t.Errorf("Ожидалось 3 FETCH по 2 строки, а было %d", testStreamDriver.fetches)
+	}
+	execs := testStreamDriver.execs
+	if len(execs) != 2 || !strings.HasSuffix(execs[0], "NO SCROLL CURSOR FOR SELECT id, name FROM users WHERE id > $1") ||
+		!strings.HasPrefix(execs[1], "CLOSE btools_cursor_") {
+		t.Errorf("Ожидались DECLARE и CLOSE курсора, а получили %v", execs)
+	}
+}
+
+func TestIsScannable(t *testing.T) {
+	cases := map[string]struct {
+		typ      interface{}
+		expected bool
+	}{
+		"int64":      {int64(0), true},
+		"time":       {time.Time{}, true},
+		"nullString": {sql.NullString{}, true},
+		"struct":     {streamUser{}, false},
+	}
+	for name, c := range cases {
+		if got := isScannable(reflect.TypeOf(c.typ)); got != c.expected {
+			t.Errorf("%s: ожидалось %v, а получили %v", name, c.expected, got)
+		}
+	}
+}
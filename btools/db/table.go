@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Служебные колонки, которые BaseRepository заполняет сам при включённых опциях.
+const (
+	ColumnCreatedAt = "created_at"
+	ColumnUpdatedAt = "updated_at"
+	ColumnDeletedAt = "deleted_at"
+	ColumnCreatedBy = "created_by"
+	ColumnUpdatedBy = "updated_by"
+)
+
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	table      string
+	softDelete bool
+	audit      bool
+}
+
+// WithTable включает встроенную реализацию CRUD в BaseRepository для таблицы name.
+// Колонки берутся из тегов `db`, первичный ключ - поля с тегом `pk:"true"` или колонка id.
+// Целочисленное поле с тегом `version:"true"` включает оптимистическую блокировку в Update.
+func WithTable(name string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.table = name
+	}
+}
+
+// WithSoftDelete: Delete выставляет deleted_at вместо удаления строки,
+// а чтение по умолчанию пропускает удалённые строки (см. WithDeleted).
+func WithSoftDelete() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.softDelete = true
+	}
+}
+
+// WithAudit: Create и Update сами заполняют created_at/updated_at и
+// created_by/updated_by (автор берётся из контекста, см. WithActor), если такие поля есть в структуре.
+func WithAudit() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.audit = true
+	}
+}
+
+type column struct {
+	name    string
+	index   []int
+	pk      bool
+	version bool
+}
+
+type tableMeta struct {
+	table      string
+	columns    []column
+	pk         []column
+	keyIndex   [][]int // индексы полей составного ключа K для каждой колонки pk
+	version    *column
+	byName     map[string]column
+	softDelete bool
+	audit      bool
+}
+
+func newTableMeta(typ reflect.Type, opts repositoryOptions) (*tableMeta, error) {
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("repository entity must be a struct, got %s", typ)
+	}
+
+	meta := &tableMeta{
+		table:      opts.table,
+		byName:     make(map[string]column),
+		softDelete: opts.softDelete,
+		audit:      opts.audit,
+	}
+	meta.collect(typ, nil)
+
+	if len(meta.pk) == 0 {
+		if id, ok := meta.byName["id"]; ok {
+			id.pk = true
+			meta.pk = []column{id}
+			for i := range meta.columns {
+				if meta.columns[i].name == "id" {
+					meta.columns[i].pk = true
+				}
+			}
+		}
+	}
+	if len(meta.pk) == 0 {
+		return nil, fmt.Errorf("repository entity %s has no primary key: tag a field with pk:\"true\"", typ)
+	}
+	return meta, nil
+}
+
+func (m *tableMeta) collect(typ reflect.Type, parent []int) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int(nil), parent...), i)
+
+		tag := field.Tag.Get("db")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			m.collect(field.Type, index)
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		col := column{
+			name:    name,
+			index:   index,
+			pk:      field.Tag.Get("pk") == "true",
+			version: field.Tag.Get("version") == "true",
+		}
+		m.columns = append(m.columns, col)
+		m.byName[name] = col
+		if col.pk {
+			m.pk = append(m.pk, col)
+		}
+		if col.version {
+			m.version = &col
+		}
+	}
+}
+
+func (m *tableMeta) has(name string) bool {
+	_, ok := m.byName[name]
+	return ok
+}
+
+func (m *tableMeta) selectList() string {
+	names := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// where строит условие по первичному ключу, начиная с плейсхолдера $start.
+func (m *tableMeta) where(ctx context.Context, start int) string {
+	parts := make([]string, len(m.pk))
+	for i, c := range m.pk {
+		parts[i] = fmt.Sprintf("%s = $%d", c.name, start+i)
+	}
+	if m.softDelete && !includeDeleted(ctx) {
+		parts = append(parts, ColumnDeletedAt+" IS NULL")
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func (m *tableMeta) pkArgs(entity reflect.Value) []interface{} {
+	args := make([]interface{}, len(m.pk))
+	for i, c := range m.pk {
+		args[i] = entity.FieldByIndex(c.index).Interface()
+	}
+	return args
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// bindKey проверяет, что тип ключа подходит к первичному ключу. Для составного ключа
+// K должен быть структурой с тегами `db`, совпадающими с колонками pk. Структура-ключ
+// допустима и для одной колонки; значения драйвера (driver.Valuer, time.Time) передаются как есть.
+func (m *tableMeta) bindKey(keyType reflect.Type) error {
+	if len(m.pk) == 1 && !isKeyStruct(keyType) {
+		return nil
+	}
+	if keyType.Kind() != reflect.Struct {
+		return fmt.Errorf("table %s has composite primary key, key type %s must be a struct", m.table, keyType)
+	}
+
+	fields := make(map[string][]int)
+	for i := 0; i < keyType.NumField(); i++ {
+		field := keyType.Field(i)
+		name := field.Tag.Get("db")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Index
+	}
+
+	m.keyIndex = make([][]int, len(m.pk))
+	for i, c := range m.pk {
+		index, ok := fields[c.name]
+		if !ok {
+			return fmt.Errorf("key type %s has no field for primary key column %s", keyType, c.name)
+		}
+		m.keyIndex[i] = index
+	}
+	return nil
+}
+
+func isKeyStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	return !t.Implements(valuerType) && !reflect.PointerTo(t).Implements(valuerType)
+}
+
+func (m *tableMeta) keyArgs(key any) []interface{} {
+	if m.keyIndex == nil {
+		return []interface{}{key}
+	}
+	v := reflect.ValueOf(key)
+	args := make([]interface{}, len(m.keyIndex))
+	for i, index := range m.keyIndex {
+		args[i] = v.FieldByIndex(index).Interface()
+	}
+	return args
+}
+
+func (m *tableMeta) isAuditColumn(name string) bool {
+	if !m.audit {
+		return false
+	}
+	switch name {
+	case ColumnCreatedAt, ColumnUpdatedAt, ColumnCreatedBy, ColumnUpdatedBy:
+		return true
+	}
+	return false
+}
+
+type deletedCtxKey struct{}
+
+// WithDeleted включает в выборку строки, помеченные как удалённые (для WithSoftDelete).
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletedCtxKey{}, true)
+}
+
+func includeDeleted(ctx context.Context) bool {
+	v, _ := ctx.Value(deletedCtxKey{}).(bool)
+	return v
+}
+
+type actorCtxKey struct{}
+
+// WithActor сохраняет в контексте автора изменений для колонок created_by/updated_by.
+// middleware.JWTMiddleware делает это сам, если задан JwtMiddlewareConfig.ActorField.
+func WithActor(ctx context.Context, actor any) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) (any, bool) {
+	actor := ctx.Value(actorCtxKey{})
+	return actor, actor != nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var tenantLogger = log.NewSubLogger("tenant")
+
+// TenantMode - способ изоляции арендаторов.
+type TenantMode int
+
+const (
+	// TenantSchema - у каждого арендатора своя схема, в транзакции выставляется search_path.
+	TenantSchema TenantMode = iota + 1
+	// TenantRLS - общие таблицы, в транзакции выставляется настройка для политик row-level security:
+	//	CREATE POLICY tenant_isolation ON orders USING (tenant_id = current_setting('app.tenant_id'));
+	TenantRLS
+)
+
+const (
+	defaultTenantSchemaPrefix = "tenant_"
+	defaultTenantVariable     = "app.tenant_id"
+)
+
+var (
+	// ErrNoTenant - в контексте нет арендатора, а TenancyConfig.Required включён.
+	ErrNoTenant = errors.New("tenant is not set in context")
+	// ErrTenantStreaming - построчное чтение вне транзакции не может держать настройки арендатора.
+	ErrTenantStreaming = errors.New("streaming with tenancy requires a transaction: use StreamCursor inside Transaction")
+
+	// допускает UUID; значение RLS передаётся параметром, а имя схемы экранируется
+	// pq.QuoteIdentifier, поэтому дефис безопасен в обоих режимах
+	tenantIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,48}$`)
+)
+
+// TenancyConfig - настройки мультиарендности. Арендатор берётся из контекста (см. WithTenant)
+// и применяется через SET LOCAL, поэтому действует только до конца транзакции и не остаётся
+// на соединении, вернувшемся в пул. Запросы через Database вне транзакции для этого
+// выполняются в короткой транзакции.
+type TenancyConfig struct {
+	Mode TenantMode
+	// SchemaPrefix - префикс схемы для TenantSchema (по умолчанию "tenant_").
+	// search_path = "<prefix><tenant>", public.
+	SchemaPrefix string
+	// Variable - настройка для TenantRLS (по умолчанию "app.tenant_id").
+	Variable string
+	// Required: запрос без арендатора в контексте завершается ErrNoTenant.
+	// Иначе такой запрос выполняется без переключения (search_path по умолчанию, настройка не задана).
+	Required bool
+}
+
+type tenantCtxKey struct{}
+
+// WithTenant сохраняет в контексте арендатора. Идентификатор может содержать только латиницу,
+// цифры, подчёркивание и дефис (например, UUID).
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantCtxKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// tenantSettings возвращает настройку и значение для set_config или пустую строку,
+// если переключать арендатора не нужно.
+func (c *TenancyConfig) tenantSettings(ctx context.Context) (string, string, error) {
+	if c == nil {
+		return "", "", nil
+	}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		if c.Required {
+			return "", "", ErrNoTenant
+		}
+		return "", "", nil
+	}
+	if !tenantIDRegex.MatchString(tenant) {
+		return "", "", fmt.Errorf("invalid tenant id %q", tenant)
+	}
+
+	switch c.Mode {
+	case TenantSchema:
+		prefix := c.SchemaPrefix
+		if prefix == "" {
+			prefix = defaultTenantSchemaPrefix
+		}
+		return "search_path", pq.QuoteIdentifier(prefix+tenant) + ", public", nil
+	case TenantRLS:
+		variable := c.Variable
+		if variable == "" {
+			variable = defaultTenantVariable
+		}
+		return variable, tenant, nil
+	default:
+		return "", "", fmt.Errorf("unknown tenant mode %d", c.Mode)
+	}
+}
+
+// applyTenant выставляет настройки арендатора до конца транзакции tx.
+func (d *Database) applyTenant(ctx context.Context, tx *sqlx.Tx) error {
+	name, value, err := d.tenancy.tenantSettings(ctx)
+	if err != nil || name == "" {
+		return err
+	}
+	// set_config(..., true) - то же, что SET LOCAL, но с параметрами вместо склейки строки
+	if _, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", name, value); err != nil {
+		tenantLogger.Error(err, "failed to set %s for tenant", name)
+		return WrapError(err)
+	}
+	return nil
+}
+
+// withTenant выполняет fn на conn, а если в контексте есть арендатор - в короткой транзакции
+// с его настройками.
+func (d *Database) withTenant(ctx context.Context, conn *sqlx.DB, fn func(q sqlx.ExtContext) error) error {
+	name, _, err := d.tenancy.tenantSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fn(conn)
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := d.applyTenant(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTenantSettings(t *testing.T) {
+	ctx := WithTenant(context.Background(), "acme")
+
+	schema := &TenancyConfig{Mode: TenantSchema}
+	name, value, err := schema.tenantSettings(ctx)
+	if err != nil || name != "search_path" || value != `"tenant_acme", public` {
+		t.Errorf("Неверный search_path: %s=%s, %v", name, value, err)
+	}
+
+	rls := &TenancyConfig{Mode: TenantRLS, Variable: "app.org"}
+	name, value, err = rls.tenantSettings(ctx)
+	if err != nil || name != "app.org" || value != "acme" {
+		t.Errorf("Неверная настройка RLS: %s=%s, %v", name, value, err)
+	}
+
+	if name, _, err := rls.tenantSettings(context.Background()); err != nil || name != "" {
+		t.Errorf("Без арендатора ничего переключать не нужно, а получили %s, %v", name, err)
+	}
+	required := &TenancyConfig{Mode: TenantRLS, Required: true}
+	if _, _, err := required.tenantSettings(context.Background()); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Ожидалась ErrNoTenant, а получили %v", err)
+	}
+	if _, _, err := schema.tenantSettings(WithTenant(ctx, `x"; DROP SCHEMA public; --`)); err == nil {
+		t.Errorf("Ожидалась ошибка для недопустимого идентификатора арендатора")
+	}
+
+	uuidCtx := WithTenant(ctx, "3f2b8c1e-9a4d-4e7b-8c2a-1d5e6f7a8b9c")
+	if _, value, err := schema.tenantSettings(uuidCtx); err != nil || value != `"tenant_3f2b8c1e-9a4d-4e7b-8c2a-1d5e6f7a8b9c", public` {
+		t.Errorf("UUID должен подходить для схемы: %s, %v", value, err)
+	}
+	if _, value, err := rls.tenantSettings(uuidCtx); err != nil || value != "3f2b8c1e-9a4d-4e7b-8c2a-1d5e6f7a8b9c" {
+		t.Errorf("UUID должен подходить для RLS: %s, %v", value, err)
+	}
+
+	var disabled *TenancyConfig
+	if name, _, err := disabled.tenantSettings(ctx); err != nil || name != "" {
+		t.Errorf("Без TenancyConfig арендатор игнорируется, а получили %s, %v", name, err)
+	}
+}
+
+func TestDatabaseTenant(t *testing.T) {
+	d := NewDatabaseFromDB(openStreamDB(t))
+	d.tenancy = &TenancyConfig{Mode: TenantRLS}
+
+	if _, err := d.ExecContext(context.Background(), "DELETE FROM orders"); err != nil {
+		t.Fatalf("ExecContext вернул ошибку: %v", err)
+	}
+	ctx := WithTenant(context.Background(), "acme")
+	if _, err := d.ExecContext(ctx, "DELETE FROM orders"); err != nil {
+		t.Fatalf("ExecContext вернул ошибку: %v", err)
+	}
+	expected := []string{"DELETE FROM orders", "SELECT set_config($1, $2, true)", "DELETE FROM orders"}
+	if !reflect.DeepEqual(testStreamDriver.execs, expected) {
+		t.Errorf("Ожидались запросы %v, а получили %v", expected, testStreamDriver.execs)
+	}
+
+	if _, err := d.QueryxContext(ctx, "SELECT id FROM orders"); !errors.Is(err, ErrTenantStreaming) {
+		t.Errorf("Ожидалась ErrTenantStreaming, а получили %v", err)
+	}
+}
@@ -2,9 +2,9 @@ package db
 
 import (
 	"context"
-	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/seemyown/backend-toolkit/btools/exc"
+	"sync"
 	"time"
 )
 
@@ -15,29 +15,82 @@ type Transaction interface {
 }
 
 type trx struct {
-	db *sqlx.DB
+	conn *Database
 }
 
 func NewTrx(db *Database) Transaction {
-	return &trx{db: db.DB}
+	return &trx{conn: db}
 }
 
 func (t *trx) Exec(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
-	tx, err := t.db.BeginTxx(ctx, nil)
+	return t.conn.observe(ctx, QueryKindTransaction, "", nil, func(ctx context.Context) (int64, error) {
+		return -1, t.exec(ctx, fn)
+	})
+}
+
+func (t *trx) exec(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := t.conn.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		log.Error(err, "Error starting transaction")
 		return exc.RepositoryError("transaction_begin_error")
 	}
+	if err := t.conn.applyTenant(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	active := &activeTx{db: t.conn}
+	activeTxs.Store(tx, active)
+	defer activeTxs.Delete(tx)
 	startTime := time.Now()
 	if err := fn(tx); err != nil {
 		log.Error(err, "Error executing transaction. Rollback...")
 		_ = tx.Rollback()
 		return err
 	}
-	log.Info(fmt.Sprintf("Transaction finished in %f seconds", time.Since(startTime).Seconds()))
+	log.Info("Transaction finished in %f seconds", time.Since(startTime).Seconds())
 	if err := tx.Commit(); err != nil {
 		log.Error(err, "Error committing transaction")
 		return exc.RepositoryError("transaction_commit_error")
 	}
+	active.hooks.run()
 	return nil
 }
+
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (h *commitHooks) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns = nil
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// activeTx - транзакция, открытая через Transaction.Exec: её Database и хуки после коммита.
+type activeTx struct {
+	db    *Database
+	hooks commitHooks
+}
+
+// activeTxs - транзакции, открытые через Transaction.Exec.
+var activeTxs sync.Map
+
+// AfterCommit откладывает fn до успешного коммита транзакции; при откате fn не вызывается.
+// Работает для транзакций, открытых через Transaction.Exec. Для остальных возвращает false,
+// и вызывающий сам решает, что делать.
+func AfterCommit(tx *sqlx.Tx, fn func()) bool {
+	v, ok := activeTxs.Load(tx)
+	if !ok {
+		return false
+	}
+	hooks := &v.(*activeTx).hooks
+	hooks.mu.Lock()
+	hooks.fns = append(hooks.fns, fn)
+	hooks.mu.Unlock()
+	return true
+}
@@ -0,0 +1,59 @@
+// Команда errcodes печатает каталог кодов ошибок btools (exc, db и middleware) в Markdown или JSON.
+//
+//	go run github.com/seemyown/backend-toolkit/btools/exc/cmd/errcodes -format md -o docs/errors.md
+//
+// Коды сервиса в каталог не попадут: для них соберите такую же команду, импортирующую пакеты сервиса.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	_ "github.com/seemyown/backend-toolkit/btools/db"
+	"github.com/seemyown/backend-toolkit/btools/exc"
+	_ "github.com/seemyown/backend-toolkit/btools/fiber/middleware"
+)
+
+func main() {
+	format := flag.String("format", "md", "output format: md or json")
+	output := flag.String("o", "", "output file (default stdout)")
+	langs := flag.String("langs", "", "comma separated languages (default all)")
+	flag.Parse()
+
+	if err := run(*format, *output, *langs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(format, output, langs string) error {
+	if err := exc.Validate(); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	var languages []string
+	if langs != "" {
+		languages = strings.Split(langs, ",")
+	}
+	switch format {
+	case "md", "markdown":
+		return exc.DefaultRegistry.WriteMarkdown(w, languages...)
+	case "json":
+		return exc.DefaultRegistry.WriteJSON(w, languages...)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,229 @@
+package exc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var codeRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ErrDuplicateCode - код ошибки объявлен больше одного раза.
+var ErrDuplicateCode = errors.New("duplicate error code")
+
+// CodeDefinition - объявление кода ошибки: HTTP-статус, описание для документации
+// и сообщения по умолчанию, которые попадают в DefaultCatalog.
+type CodeDefinition struct {
+	Code        string            `json:"code"`
+	Status      int               `json:"status"`
+	Description string            `json:"description"`
+	Messages    map[string]string `json:"messages,omitempty"`
+}
+
+// Registry - единый список кодов ошибок сервиса.
+type Registry struct {
+	mu     sync.RWMutex
+	codes  map[string]CodeDefinition
+	errors []error
+}
+
+func NewRegistry() *Registry {
+	return &Registry{codes: make(map[string]CodeDefinition)}
+}
+
+// DefaultRegistry содержит коды exc, db и middleware; сервис добавляет свои через Register.
+var DefaultRegistry = NewRegistry()
+
+// Register объявляет коды в DefaultRegistry.
+func Register(defs ...CodeDefinition) error {
+	return DefaultRegistry.Register(defs...)
+}
+
+// MustRegister объявляет коды в DefaultRegistry и паникует при повторном объявлении.
+func MustRegister(defs ...CodeDefinition) {
+	if err := Register(defs...); err != nil {
+		panic(err)
+	}
+}
+
+// Validate проверяет DefaultRegistry. Вызывается при старте сервиса, после регистрации всех кодов.
+func Validate() error {
+	return DefaultRegistry.Validate()
+}
+
+// FromCode создаёт ошибку по объявленному в DefaultRegistry коду со статусом и сообщением по умолчанию.
+// Для необъявленного кода возвращает ошибку со статусом 500.
+func FromCode(code string) *Error {
+	def, ok := DefaultRegistry.Lookup(code)
+	if !ok {
+		return NewAppError(ErrorName(http.StatusInternalServerError), code, "", http.StatusInternalServerError)
+	}
+	return NewAppError(ErrorName(def.Status), code, "", def.Status)
+}
+
+// Register объявляет коды. Повторное объявление не перезаписывает первое: возвращается
+// ErrDuplicateCode, и та же ошибка потом попадает в Validate. Сообщения добавляются в DefaultCatalog.
+func (r *Registry) Register(defs ...CodeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for _, def := range defs {
+		if _, ok := r.codes[def.Code]; ok {
+			err := fmt.Errorf("%w: %s", ErrDuplicateCode, def.Code)
+			r.errors = append(r.errors, err)
+			errs = append(errs, err)
+			continue
+		}
+		r.codes[def.Code] = def
+		for lang, msg := range def.Messages {
+			DefaultCatalog.Add(lang, map[string]string{def.Code: msg})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) Lookup(code string) (CodeDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.codes[code]
+	return def, ok
+}
+
+// Codes возвращает объявленные коды, отсортированные по имени.
+func (r *Registry) Codes() []CodeDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]CodeDefinition, 0, len(r.codes))
+	for _, def := range r.codes {
+		result = append(result, def)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result
+}
+
+// Validate возвращает все найденные проблемы: повторные объявления, коды не в snake_case,
+// недопустимые статусы и коды без сообщения на языке по умолчанию каталога.
+func (r *Registry) Validate() error {
+	r.mu.RLock()
+	errs := append([]error(nil), r.errors...)
+	r.mu.RUnlock()
+
+	for _, def := range r.Codes() {
+		if !codeRegex.MatchString(def.Code) {
+			errs = append(errs, fmt.Errorf("error code %q must be snake_case", def.Code))
+		}
+		if def.Status < 400 || def.Status > 599 {
+			errs = append(errs, fmt.Errorf("error code %s has invalid HTTP status %d", def.Code, def.Status))
+		}
+		if _, ok := DefaultCatalog.Lookup(DefaultCatalog.Fallback(), def.Code); !ok {
+			errs = append(errs, fmt.Errorf("error code %s has no %s message", def.Code, DefaultCatalog.Fallback()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// codeDoc - код вместе с сообщениями из каталога, для документации.
+type codeDoc struct {
+	CodeDefinition
+	Messages map[string]string `json:"messages"`
+}
+
+func (r *Registry) docs(langs []string) []codeDoc {
+	if len(langs) == 0 {
+		langs = DefaultCatalog.Languages()
+	}
+	defs := r.Codes()
+	result := make([]codeDoc, len(defs))
+	for i, def := range defs {
+		doc := codeDoc{CodeDefinition: def, Messages: make(map[string]string, len(langs))}
+		for _, lang := range langs {
+			if msg, ok := DefaultCatalog.Lookup(lang, def.Code); ok {
+				doc.Messages[lang] = msg
+			}
+		}
+		result[i] = doc
+	}
+	return result
+}
+
+// WriteJSON пишет каталог кодов в JSON с сообщениями на языках langs (по умолчанию - всех из каталога).
+func (r *Registry) WriteJSON(w io.Writer, langs ...string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.docs(langs))
+}
+
+// WriteMarkdown пишет каталог кодов таблицей Markdown для потребителей API.
+func (r *Registry) WriteMarkdown(w io.Writer, langs ...string) error {
+	if len(langs) == 0 {
+		langs = DefaultCatalog.Languages()
+	}
+
+	var b strings.Builder
+	b.WriteString("# Error codes\n\n| Code | HTTP status | Description |")
+	for _, lang := range langs {
+		b.WriteString(" " + lang + " |")
+	}
+	b.WriteString("\n|---|---|---|" + strings.Repeat("---|", len(langs)) + "\n")
+
+	for _, doc := range r.docs(langs) {
+		status := strings.TrimSpace(fmt.Sprintf("%d %s", doc.Status, http.StatusText(doc.Status)))
+		fmt.Fprintf(&b, "| `%s` | %s | %s |", doc.Code, status, markdownCell(doc.Description))
+		for _, lang := range langs {
+			b.WriteString(" " + markdownCell(doc.Messages[lang]) + " |")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
+
+// ErrorName - значение поля Error.Err для HTTP-статуса.
+func ErrorName(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "BadRequestError"
+	case http.StatusUnauthorized:
+		return "UnauthorizedError"
+	case http.StatusForbidden:
+		return "ForbiddenError"
+	case http.StatusNotFound:
+		return "NotFoundError"
+	case http.StatusConflict:
+		return "ConflictError"
+	case http.StatusUnprocessableEntity, http.StatusNotAcceptable:
+		return "ValidationError"
+	case http.StatusServiceUnavailable:
+		return "ServiceUnavailableError"
+	case 523:
+		return "UnreachableOrigin"
+	default:
+		return "InternalServerError"
+	}
+}
+
+func init() {
+	MustRegister(
+		CodeDefinition{Code: "bad_request", Status: http.StatusBadRequest, Description: "Malformed request"},
+		CodeDefinition{Code: "unauthorized", Status: http.StatusUnauthorized, Description: "Authentication is required"},
+		CodeDefinition{Code: "forbidden", Status: http.StatusForbidden, Description: "The caller has no access to the resource"},
+		CodeDefinition{Code: "not_found", Status: http.StatusNotFound, Description: "The requested record does not exist"},
+		CodeDefinition{Code: "conflict", Status: http.StatusConflict, Description: "The request conflicts with the current state"},
+		CodeDefinition{Code: "validation_failed", Status: http.StatusUnprocessableEntity, Description: "One or more fields are invalid, see details"},
+		CodeDefinition{Code: "required", Status: http.StatusUnprocessableEntity, Description: "A required field is missing"},
+		CodeDefinition{Code: "internal_server_error", Status: http.StatusInternalServerError, Description: "Unexpected server error"},
+		CodeDefinition{Code: "service_unavailable", Status: http.StatusServiceUnavailable, Description: "A dependency is temporarily unavailable"},
+		CodeDefinition{Code: "unreachable_origin", Status: 523, Description: "An upstream service cannot be reached"},
+	)
+}
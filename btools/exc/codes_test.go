@@ -0,0 +1,89 @@
+package exc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistryDuplicate(t *testing.T) {
+	r := NewRegistry()
+	def := CodeDefinition{Code: "order_closed", Status: 409, Description: "Order is closed", Messages: map[string]string{"ru": "Заказ закрыт"}}
+	if err := r.Register(def); err != nil {
+		t.Fatalf("Первое объявление не должно возвращать ошибку: %v", err)
+	}
+	if err := r.Register(CodeDefinition{Code: "order_closed", Status: 400}); !errors.Is(err, ErrDuplicateCode) {
+		t.Errorf("Ожидалась ErrDuplicateCode, а получили %v", err)
+	}
+	if got, _ := r.Lookup("order_closed"); got.Status != 409 {
+		t.Errorf("Повторное объявление не должно перезаписывать первое, а получили статус %d", got.Status)
+	}
+	if err := r.Validate(); !errors.Is(err, ErrDuplicateCode) {
+		t.Errorf("Validate должна сообщать о повторном объявлении, а получили %v", err)
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Errorf("Встроенные коды не должны содержать ошибок: %v", err)
+	}
+
+	r := NewRegistry()
+	_ = r.Register(
+		CodeDefinition{Code: "BadCode", Status: 400, Messages: map[string]string{"ru": "x"}},
+		CodeDefinition{Code: "no_message_code", Status: 200},
+	)
+	err := r.Validate()
+	if err == nil {
+		t.Fatalf("Ожидались ошибки валидации")
+	}
+	for _, part := range []string{"BadCode", "invalid HTTP status 200", "no_message_code has no ru message"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("Ожидалось %q в %q", part, err.Error())
+		}
+	}
+}
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(
+		CodeDefinition{Code: "order_not_paid", Status: 402, Description: "Order | unpaid", Messages: map[string]string{"ru": "Заказ не оплачен", "en": "Order is not paid"}},
+		CodeDefinition{Code: "bad_request", Status: 400, Description: "Malformed request"},
+	)
+
+	var md bytes.Buffer
+	if err := r.WriteMarkdown(&md, "ru", "en"); err != nil {
+		t.Fatalf("WriteMarkdown вернула ошибку: %v", err)
+	}
+	expected := "| `order_not_paid` | 402 Payment Required | Order \\| unpaid | Заказ не оплачен | Order is not paid |"
+	if !strings.Contains(md.String(), expected) {
+		t.Errorf("Ожидалась строка %q, а получили:\n%s", expected, md.String())
+	}
+	if strings.Index(md.String(), "bad_request") > strings.Index(md.String(), "order_not_paid") {
+		t.Errorf("Коды должны быть отсортированы")
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf, "en"); err != nil {
+		t.Fatalf("WriteJSON вернула ошибку: %v", err)
+	}
+	var docs []CodeDefinition
+	if err := json.Unmarshal(buf.Bytes(), &docs); err != nil {
+		t.Fatalf("Не удалось разобрать JSON: %v", err)
+	}
+	if len(docs) != 2 || docs[0].Messages["en"] != "Bad request" || docs[1].Status != 402 {
+		t.Errorf("Неверный JSON-каталог: %+v", docs)
+	}
+}
+
+func TestFromCode(t *testing.T) {
+	err := FromCode("not_found")
+	if err.StatusCode != 404 || err.Err != "NotFoundError" || err.Message != "Запись не найдена" {
+		t.Errorf("Неверная ошибка по коду: %+v", err)
+	}
+	if unknown := FromCode("no_such_code"); unknown.StatusCode != 500 {
+		t.Errorf("Для необъявленного кода ожидался статус 500, а получили %d", unknown.StatusCode)
+	}
+}
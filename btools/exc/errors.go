@@ -2,27 +2,142 @@ package exc
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 )
 
+// FieldViolation - нарушение правила валидации одного поля запроса.
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
 type Error struct {
 	Err        string `json:"error"`
 	Code       string `json:"code"`
 	StatusCode int    `json:"status_code"`
 	Message    string `json:"message"`
+	Field      string `json:"field,omitempty"`
+	// Details - список нарушений по полям, чтобы вернуть все ошибки валидации разом
+	Details []FieldViolation `json:"details,omitempty"`
+	// Meta - произвольные данные для клиента (лимиты, идентификаторы и т.п.)
+	Meta map[string]any `json:"meta,omitempty"`
+
+	// cause - исходная ошибка; клиенту не отдаётся, но попадает в логи через Error()
+	cause error
+	// sentinel - ошибка из набора ErrNotFound, ErrConflict и т.п., см. Is
+	sentinel bool
+	// stack - стек вызова на момент создания, см. CaptureStack
+	stack []uintptr
+}
+
+// Сентинелы для errors.Is: совпадают с любой ошибкой того же вида независимо от Code,
+// в том числе с db.RepositoryError. With* всегда возвращают копию и не меняют исходную ошибку,
+// поэтому из обработчика можно вернуть exc.ErrNotFound.WithCause(err).
+//
+// ErrNotFound не совпадает с голым sql.ErrNoRows: errors.Is вызывает Is у проверяемой ошибки,
+// а не у target, и exc не зависит от database/sql. Ошибки из базы проверяйте через
+// db.IsNotFound или оборачивайте db.WrapError - его результат с ErrNotFound совпадает.
+var (
+	ErrBadRequest         = newSentinel("BadRequestError", "bad_request", http.StatusBadRequest)
+	ErrUnauthorized       = newSentinel("UnauthorizedError", "unauthorized", http.StatusUnauthorized)
+	ErrForbidden          = newSentinel("ForbiddenError", "forbidden", http.StatusForbidden)
+	ErrNotFound           = newSentinel("NotFoundError", "not_found", http.StatusNotFound)
+	ErrConflict           = newSentinel("ConflictError", "conflict", http.StatusConflict)
+	ErrValidation         = newSentinel("ValidationError", "validation_failed", http.StatusUnprocessableEntity)
+	ErrInternal           = newSentinel("InternalServerError", "internal_server_error", http.StatusInternalServerError)
+	ErrServiceUnavailable = newSentinel("ServiceUnavailableError", "service_unavailable", http.StatusServiceUnavailable)
+)
+
+func newSentinel(err, code string, statusCode int) *Error {
+	e := NewAppError(err, code, "", statusCode)
+	e.sentinel = true
+	return e
 }
 
 func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("[%d] - %s (%s): %v", e.StatusCode, e.Code, e.Message, e.cause)
+	}
 	return fmt.Sprintf("[%d] - %s (%s)", e.StatusCode, e.Code, e.Message)
 }
 
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is для errors.Is: с сентинелом ошибка совпадает по виду (HTTP-статусу; 406 и 422 - оба
+// ValidationError), с другой *Error - по Code и StatusCode.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.sentinel {
+		return sameKind(e.StatusCode, t.StatusCode)
+	}
+	return e.Code == t.Code && e.StatusCode == t.StatusCode
+}
+
+func sameKind(a, b int) bool {
+	isValidation := func(status int) bool {
+		return status == http.StatusUnprocessableEntity || status == http.StatusNotAcceptable
+	}
+	return a == b || (isValidation(a) && isValidation(b))
+}
+
+// clone возвращает копию ошибки, чтобы With* не меняли исходную: её может разделять
+// несколько горутин (сентинел или ошибка сервиса вида var ErrX = exc.NotFoundError(...)).
+func (e *Error) clone() *Error {
+	c := *e
+	c.Details = slices.Clone(e.Details)
+	c.Meta = maps.Clone(e.Meta)
+	if c.sentinel {
+		c.sentinel = false
+		c.captureStack()
+	}
+	return &c
+}
+
+// WithCause сохраняет исходную ошибку, доступную через errors.Is/errors.As.
+func (e *Error) WithCause(cause error) *Error {
+	e = e.clone()
+	e.cause = cause
+	return e
+}
+
+// WithDetails добавляет нарушения по полям.
+func (e *Error) WithDetails(details ...FieldViolation) *Error {
+	e = e.clone()
+	for _, d := range details {
+		d.Message = catalogMessage(d.Code, d.Message)
+		e.Details = append(e.Details, d)
+	}
+	return e
+}
+
+// WithMeta добавляет значение в Meta.
+func (e *Error) WithMeta(key string, value any) *Error {
+	e = e.clone()
+	if e.Meta == nil {
+		e.Meta = make(map[string]any)
+	}
+	e.Meta[key] = value
+	return e
+}
+
+// NewAppError создаёт ошибку. Если message пустое, сообщение берётся из DefaultCatalog по code.
 func NewAppError(err, code, message string, statusCode int) *Error {
-	return &Error{
+	e := &Error{
 		Err:        err,
 		Code:       code,
 		StatusCode: statusCode,
-		Message:    message,
+		Message:    catalogMessage(code, message),
 	}
+	e.captureStack()
+	return e
 }
 
 func BadRequestError(code, msg string) *Error {
@@ -62,5 +177,12 @@ func RepositoryError(msg string) *Error {
 }
 
 func ValidationError(code, field, msg string) *Error {
-	return NewAppError("ValidationError", code, fmt.Sprintf("%s: [%s]", msg, field), http.StatusUnprocessableEntity)
+	err := NewAppError("ValidationError", code, msg, http.StatusUnprocessableEntity)
+	err.Field = field
+	return err.WithDetails(FieldViolation{Field: field, Code: code, Message: err.Message})
+}
+
+// ValidationErrors - ошибка валидации сразу по нескольким полям.
+func ValidationErrors(code, msg string, violations ...FieldViolation) *Error {
+	return NewAppError("ValidationError", code, msg, http.StatusUnprocessableEntity).WithDetails(violations...)
 }
@@ -0,0 +1,115 @@
+package exc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidationError(t *testing.T) {
+	err := ValidationError("invalid_email", "email", "Некорректный email")
+	if err.Message != "Некорректный email" || err.Field != "email" {
+		t.Errorf("Поле не должно попадать в сообщение, а получили %q / %q", err.Message, err.Field)
+	}
+	if len(err.Details) != 1 || err.Details[0].Field != "email" {
+		t.Errorf("Ожидалось одно нарушение для email, а получили %+v", err.Details)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	err := ValidationErrors("validation_failed", "Проверьте поля",
+		FieldViolation{Field: "email", Code: "required", Message: "Обязательное поле"},
+		FieldViolation{Field: "age", Code: "min", Message: "Не меньше 18"},
+	).WithMeta("form", "signup")
+
+	data, _ := json.Marshal(err)
+	var body map[string]any
+	_ = json.Unmarshal(data, &body)
+	if details, ok := body["details"].([]any); !ok || len(details) != 2 {
+		t.Errorf("Ожидалось два нарушения в JSON, а получили %s", data)
+	}
+	if meta, ok := body["meta"].(map[string]any); !ok || meta["form"] != "signup" {
+		t.Errorf("Ожидались метаданные в JSON, а получили %s", data)
+	}
+}
+
+func TestErrorCause(t *testing.T) {
+	err := InternalServerError("Не удалось прочитать файл").WithCause(io.ErrUnexpectedEOF)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Исходная ошибка должна быть доступна через errors.Is")
+	}
+	if !strings.HasSuffix(err.Error(), io.ErrUnexpectedEOF.Error()) {
+		t.Errorf("Исходная ошибка должна попадать в текст для логов, а получили %q", err.Error())
+	}
+
+	data, _ := json.Marshal(err)
+	if strings.Contains(string(data), "unexpected EOF") {
+		t.Errorf("Исходная ошибка не должна уходить клиенту: %s", data)
+	}
+	if strings.Contains(string(data), "details") || strings.Contains(string(data), "meta") {
+		t.Errorf("Пустые details и meta не должны сериализоваться: %s", data)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("get user: %w", NotFoundError("user_not_found", "Пользователь не найден"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ошибка 404 должна совпадать с ErrNotFound")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Errorf("Ошибка 404 не должна совпадать с ErrConflict")
+	}
+	if !errors.Is(err, NotFoundError("user_not_found", "")) || errors.Is(err, NotFoundError("order_not_found", "")) {
+		t.Errorf("Обычные ошибки должны сравниваться по коду")
+	}
+	if !errors.Is(NewAppError("ValidationError", "not_null_violation", "", 406), ErrValidation) {
+		t.Errorf("406 и 422 - оба ошибки валидации")
+	}
+
+	if errors.Is(sql.ErrNoRows, ErrNotFound) {
+		t.Errorf("sql.ErrNoRows не может совпадать с ErrNotFound, см. документацию к нему")
+	}
+
+	wrapped := ErrNotFound.WithCause(io.EOF).WithMeta("id", 42)
+	if ErrNotFound.Unwrap() != nil || ErrNotFound.Meta != nil {
+		t.Errorf("With* не должны менять сентинел")
+	}
+	if !errors.Is(wrapped, ErrNotFound) || !errors.Is(wrapped, io.EOF) {
+		t.Errorf("Копия сентинела должна совпадать с ним и с исходной ошибкой")
+	}
+	// ошибка сервиса вида var ErrUserNotFound = NotFoundError(...) тоже общая для всех запросов
+	errUserNotFound := NotFoundError("user_not_found", "").WithMeta("kind", "user")
+	withID := errUserNotFound.WithCause(io.EOF).WithMeta("id", 42)
+	if errUserNotFound.Unwrap() != nil || len(errUserNotFound.Meta) != 1 || withID == errUserNotFound {
+		t.Errorf("With* не должны менять исходную ошибку, а получили %+v", errUserNotFound)
+	}
+	if withID.Meta["kind"] != "user" || withID.Meta["id"] != 42 || !errors.Is(withID, errUserNotFound) {
+		t.Errorf("Копия должна сохранять данные исходной ошибки, а получили %+v", withID)
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	CaptureStack = true
+	defer func() { CaptureStack = false }()
+
+	err := InternalServerError("")
+	stack := err.StackTrace()
+	if first, _, _ := strings.Cut(stack, "\n"); !strings.HasSuffix(first, ".TestErrorStack") {
+		t.Errorf("Стек должен начинаться с места создания ошибки, а получили:\n%s", stack)
+	}
+	if NotFoundError("not_found", "").StackTrace() != "" {
+		t.Errorf("Для ошибок 4xx стек не сохраняется")
+	}
+	if ErrInternal.WithCause(io.EOF).StackTrace() == "" || ErrInternal.StackTrace() != "" {
+		t.Errorf("Стек должен сохраняться в копии сентинела, но не в нём самом")
+	}
+
+	data, _ := json.Marshal(err)
+	if strings.Contains(string(data), "TestErrorStack") {
+		t.Errorf("Стек не должен попадать в JSON: %s", data)
+	}
+}
@@ -0,0 +1,231 @@
+package exc
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales
+var defaultLocales embed.FS
+
+// DefaultLanguage - язык сообщений, если клиент не прислал Accept-Language.
+const DefaultLanguage = "ru"
+
+// DefaultCatalog - каталог сообщений, которым пользуются конструкторы exc, db.RepositoryError
+// и ErrorMiddleware. Сервис дополняет его своими кодами через Load или Add.
+var DefaultCatalog = mustDefaultCatalog()
+
+// Catalog - сообщения об ошибках по языкам, ключ - код ошибки (Error.Code).
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+	fallback string
+}
+
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{messages: make(map[string]map[string]string), fallback: fallback}
+}
+
+func mustDefaultCatalog() *Catalog {
+	c := NewCatalog(DefaultLanguage)
+	if err := c.Load(defaultLocales, "locales"); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Fallback возвращает язык, на который каталог откатывается при отсутствии перевода.
+func (c *Catalog) Fallback() string {
+	return c.fallback
+}
+
+// Load читает файлы <язык>.yaml, <язык>.yml или <язык>.json из каталога dir (удобно вместе с go:embed).
+// Каждый файл - плоский словарь "код: сообщение". Сообщения добавляются к уже загруженным,
+// одинаковые коды перезаписываются.
+func (c *Catalog) Load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("read locales dir %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		messages := make(map[string]string)
+		if ext == ".json" {
+			err = json.Unmarshal(data, &messages)
+		} else {
+			err = yaml.Unmarshal(data, &messages)
+		}
+		if err != nil {
+			return fmt.Errorf("parse locale %s: %w", entry.Name(), err)
+		}
+		c.Add(strings.TrimSuffix(entry.Name(), ext), messages)
+	}
+	return nil
+}
+
+// Languages возвращает языки каталога: сначала язык по умолчанию, затем остальные по алфавиту.
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		if lang != c.fallback {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	if _, ok := c.messages[c.fallback]; ok {
+		langs = append([]string{c.fallback}, langs...)
+	}
+	return langs
+}
+
+// Add добавляет сообщения для языка lang.
+func (c *Catalog) Add(lang string, messages map[string]string) {
+	lang = normalizeLang(lang)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[lang] == nil {
+		c.messages[lang] = make(map[string]string, len(messages))
+	}
+	for code, msg := range messages {
+		c.messages[lang][code] = msg
+	}
+}
+
+// Lookup ищет сообщение строго для lang (или его основного языка: en-US -> en), без отката.
+func (c *Catalog) Lookup(lang, code string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	lang = normalizeLang(lang)
+	if msg, ok := c.messages[lang][code]; ok {
+		return msg, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		msg, ok := c.messages[base][code]
+		return msg, ok
+	}
+	return "", false
+}
+
+// Message ищет сообщение для lang, а если перевода нет - на языке Fallback.
+func (c *Catalog) Message(lang, code string) (string, bool) {
+	if msg, ok := c.Lookup(lang, code); ok {
+		return msg, true
+	}
+	return c.Lookup(c.fallback, code)
+}
+
+// Match выбирает язык каталога по заголовку Accept-Language с учётом q-весов.
+func (c *Catalog) Match(acceptLanguage string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			return c.fallback, true
+		}
+		if _, ok := c.messages[tag]; ok {
+			return tag, true
+		}
+		if base, _, found := strings.Cut(tag, "-"); found {
+			if _, ok := c.messages[base]; ok {
+				return base, true
+			}
+		}
+	}
+	return "", false
+}
+
+type weightedTag struct {
+	tag string
+	q   float64
+}
+
+func parseAcceptLanguage(header string) []string {
+	var tags []weightedTag
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			tags = append(tags, weightedTag{tag: normalizeLang(tag), q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// Localize возвращает копию ошибки с сообщениями на языке lang из DefaultCatalog.
+// Переводятся только сообщения из каталога: пустые или совпадающие с текстом на языке
+// по умолчанию. Собственные тексты, переданные в конструктор, остаются как есть.
+func (e *Error) Localize(lang string) *Error {
+	localized := *e
+	localized.Message = localize(lang, e.Code, e.Message)
+	if len(e.Details) > 0 {
+		localized.Details = make([]FieldViolation, len(e.Details))
+		for i, d := range e.Details {
+			d.Message = localize(lang, d.Code, d.Message)
+			localized.Details[i] = d
+		}
+	}
+	return &localized
+}
+
+func localize(lang, code, message string) string {
+	if code == "" {
+		return message
+	}
+	if message != "" {
+		if original, ok := DefaultCatalog.Lookup(DefaultCatalog.Fallback(), code); !ok || original != message {
+			return message
+		}
+	}
+	if msg, ok := DefaultCatalog.Message(lang, code); ok {
+		return msg
+	}
+	return message
+}
+
+// catalogMessage - сообщение по умолчанию для конструкторов, если текст не передан.
+func catalogMessage(code, message string) string {
+	if message != "" {
+		return message
+	}
+	msg, _ := DefaultCatalog.Message(DefaultCatalog.Fallback(), code)
+	return msg
+}
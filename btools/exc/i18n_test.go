@@ -0,0 +1,77 @@
+package exc
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCatalogLoad(t *testing.T) {
+	c := NewCatalog("ru")
+	err := c.Load(fstest.MapFS{
+		"i18n/ru.yaml":   {Data: []byte("user_not_found: Пользователь не найден\n")},
+		"i18n/en.json":   {Data: []byte(`{"user_not_found": "User not found"}`)},
+		"i18n/README.md": {Data: []byte("не перевод")},
+	}, "i18n")
+	if err != nil {
+		t.Fatalf("Load вернула ошибку: %v", err)
+	}
+
+	if msg, _ := c.Message("en-GB", "user_not_found"); msg != "User not found" {
+		t.Errorf("en-GB должен откатываться на en, а получили %q", msg)
+	}
+	if msg, _ := c.Message("de", "user_not_found"); msg != "Пользователь не найден" {
+		t.Errorf("Без перевода ожидалось сообщение на языке по умолчанию, а получили %q", msg)
+	}
+	if _, ok := c.Lookup("de", "user_not_found"); ok {
+		t.Errorf("Lookup не должен откатываться на язык по умолчанию")
+	}
+
+	if err := c.Load(fstest.MapFS{"bad/ru.yaml": {Data: []byte("- not a map")}}, "bad"); err == nil {
+		t.Errorf("Ожидалась ошибка разбора")
+	}
+}
+
+func TestCatalogMatch(t *testing.T) {
+	c := NewCatalog("ru")
+	c.Add("ru", map[string]string{"x": "x"})
+	c.Add("en", map[string]string{"x": "x"})
+
+	cases := map[string]string{
+		"en-US,en;q=0.9":       "en",
+		"de;q=0.9, ru;q=0.8":   "ru",
+		"fr, en;q=0.1, ru;q=0": "en",
+		"*":                    "ru",
+	}
+	for header, expected := range cases {
+		if lang, ok := c.Match(header); !ok || lang != expected {
+			t.Errorf("%q: ожидался %s, а получили %q", header, expected, lang)
+		}
+	}
+	if _, ok := c.Match("fr"); ok {
+		t.Errorf("Неподдерживаемый язык не должен подбираться")
+	}
+}
+
+func TestErrorLocalize(t *testing.T) {
+	err := ConflictError("conflict", "")
+	if err.Message != "Конфликт с текущим состоянием" {
+		t.Errorf("Конструктор должен брать сообщение из каталога, а получили %q", err.Message)
+	}
+	if msg := err.Localize("en").Message; msg != "Conflict with the current state" {
+		t.Errorf("Ожидался перевод на английский, а получили %q", msg)
+	}
+	if err.Message != "Конфликт с текущим состоянием" {
+		t.Errorf("Localize не должен менять исходную ошибку")
+	}
+
+	custom := ConflictError("conflict", "Логин уже занят")
+	if msg := custom.Localize("en").Message; msg != "Логин уже занят" {
+		t.Errorf("Собственное сообщение не должно переводиться, а получили %q", msg)
+	}
+
+	validation := ValidationErrors("validation_failed", "", FieldViolation{Field: "email", Code: "required"})
+	localized := validation.Localize("en")
+	if localized.Message != "Validation failed" || localized.Details[0].Message != "Field is required" {
+		t.Errorf("Ожидался перевод сообщения и нарушений, а получили %+v", localized)
+	}
+}
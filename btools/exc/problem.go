@@ -0,0 +1,47 @@
+package exc
+
+import "net/http"
+
+// ProblemContentType - тип содержимого ответа по RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Problem - тело ответа application/problem+json (RFC 7807).
+// Code, Errors и Meta - члены-расширения с тем же смыслом, что и в Error.
+type Problem struct {
+	Type     string           `json:"type"`
+	Title    string           `json:"title"`
+	Status   int              `json:"status"`
+	Detail   string           `json:"detail,omitempty"`
+	Instance string           `json:"instance,omitempty"`
+	Code     string           `json:"code,omitempty"`
+	Errors   []FieldViolation `json:"errors,omitempty"`
+	Meta     map[string]any   `json:"meta,omitempty"`
+}
+
+// Problem приводит ошибку к формату RFC 7807. type собирается как typeBaseURL + Code,
+// а без typeBaseURL равен "about:blank". instance - обычно путь запроса.
+func (e *Error) Problem(typeBaseURL, instance string) *Problem {
+	problemType := "about:blank"
+	if typeBaseURL != "" && e.Code != "" {
+		problemType = typeBaseURL + e.Code
+	}
+	title := http.StatusText(e.StatusCode)
+	if title == "" {
+		title = e.Err
+	}
+
+	errs := e.Details
+	if len(errs) == 0 && e.Field != "" {
+		errs = []FieldViolation{{Field: e.Field, Code: e.Code, Message: e.Message}}
+	}
+	return &Problem{
+		Type:     problemType,
+		Title:    title,
+		Status:   e.StatusCode,
+		Detail:   e.Message,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   errs,
+		Meta:     e.Meta,
+	}
+}
@@ -0,0 +1,67 @@
+package exc
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// CaptureStack включает сохранение стека вызовов в ошибках со статусом 5xx, созданных
+// конструкторами exc (и в копиях сентинелов, например ErrInternal.WithCause). Задаётся при старте
+// сервиса. Стек доступен только через StackTrace для логов: в JSON и problem+json он не попадает.
+var CaptureStack = false
+
+const maxStackDepth = 32
+
+const excPackage = "github.com/seemyown/backend-toolkit/btools/exc."
+
+// WithStack сохраняет стек вызова независимо от CaptureStack и статуса.
+func (e *Error) WithStack() *Error {
+	e = e.clone()
+	e.stack = callers()
+	return e
+}
+
+// StackTrace возвращает стек, сохранённый при создании ошибки, или пустую строку.
+func (e *Error) StackTrace() string {
+	if len(e.stack) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+func (e *Error) captureStack() {
+	if CaptureStack && e.StatusCode >= 500 && e.stack == nil {
+		e.stack = callers()
+	}
+}
+
+// callers возвращает стек без кадров самого пакета exc, то есть начиная с места создания ошибки.
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	pcs = pcs[:n]
+
+	frames := runtime.CallersFrames(pcs)
+	skip := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, excPackage) || strings.HasSuffix(frame.File, "_test.go") {
+			break
+		}
+		skip++
+		if !more {
+			break
+		}
+	}
+	return pcs[skip:]
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultOutput = "ext_accessors.go"
+	tagKey        = "ext"
+)
+
+// accessor - одна генерируемая функция.
+type accessor struct {
+	Name   string
+	Struct string
+	Field  string
+	Type   string
+}
+
+// generate разбирает .go-файлы пакета в dir (кроме тестов и output) и возвращает
+// отформатированный исходник с аксессорами для полей с тегом ext.
+func generate(dir string, types []string, output string) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var pkgName string
+	var accessors []accessor
+	imports := make(map[string]string) // путь -> имя в файле
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") || filepath.Base(path) == output {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		pkgName = file.Name.Name
+
+		fileImports := importNames(file)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok || (len(types) > 0 && !slices.Contains(types, ts.Name.Name)) {
+					continue
+				}
+				if ts.TypeParams != nil {
+					return nil, fmt.Errorf("%s: generic structs are not supported", ts.Name.Name)
+				}
+				found, err := structAccessors(fset, ts.Name.Name, st, fileImports, imports)
+				if err != nil {
+					return nil, err
+				}
+				accessors = append(accessors, found...)
+			}
+		}
+	}
+	if pkgName == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	if len(accessors) == 0 {
+		return nil, errors.New("no fields with ext tag found")
+	}
+	if err := checkDuplicates(accessors); err != nil {
+		return nil, err
+	}
+	return render(pkgName, imports, accessors)
+}
+
+func structAccessors(fset *token.FileSet, structName string, st *ast.StructType, fileImports, used map[string]string) ([]accessor, error) {
+	var result []accessor
+	for _, field := range st.Fields.List {
+		if field.Tag == nil || len(field.Names) == 0 {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := reflect.StructTag(tag).Lookup(tagKey)
+		if !ok || name == "-" {
+			continue
+		}
+
+		var typ bytes.Buffer
+		if err := format.Node(&typ, fset, field.Type); err != nil {
+			return nil, err
+		}
+		if err := collectImports(field.Type, fileImports, used); err != nil {
+			return nil, fmt.Errorf("%s: %w", structName, err)
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				return nil, fmt.Errorf("%s.%s: ext tag on unexported field", structName, ident.Name)
+			}
+			accessorName := name
+			if accessorName == "" || len(field.Names) > 1 {
+				accessorName = structName + ident.Name
+			}
+			result = append(result, accessor{Name: accessorName, Struct: structName, Field: ident.Name, Type: typ.String()})
+		}
+	}
+	return result, nil
+}
+
+// importNames возвращает имена импортов файла: имя -> путь.
+func importNames(file *ast.File) map[string]string {
+	result := make(map[string]string)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := defaultImportName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		result[name] = path
+	}
+	return result
+}
+
+// defaultImportName угадывает имя пакета по пути импорта без загрузки пакета:
+// github.com/jackc/pgx/v5 -> pgx, gopkg.in/yaml.v3 -> yaml, github.com/redis/go-redis/v9 -> redis.
+func defaultImportName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && isMajorVersion(name) {
+		name = parts[len(parts)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+func isMajorVersion(s string) bool {
+	_, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	return strings.HasPrefix(s, "v") && err == nil
+}
+
+// collectImports добавляет в used импорты, на которые ссылается тип поля.
+func collectImports(expr ast.Expr, fileImports, used map[string]string) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		path, ok := fileImports[pkg.Name]
+		if !ok {
+			err = fmt.Errorf("unknown package %s", pkg.Name)
+			return false
+		}
+		if existing, ok := used[path]; ok && existing != pkg.Name {
+			err = fmt.Errorf("package %s is imported as both %s and %s", path, existing, pkg.Name)
+			return false
+		}
+		used[path] = pkg.Name
+		return false
+	})
+	return err
+}
+
+func checkDuplicates(accessors []accessor) error {
+	seen := make(map[string]accessor, len(accessors))
+	for _, a := range accessors {
+		if prev, ok := seen[a.Name]; ok {
+			return fmt.Errorf("accessor %s is generated for both %s.%s and %s.%s", a.Name, prev.Struct, prev.Field, a.Struct, a.Field)
+		}
+		seen[a.Name] = a
+	}
+	return nil
+}
+
+func render(pkgName string, imports map[string]string, accessors []accessor) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by extgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		// сначала стандартная библиотека, затем остальные - как у goimports
+		isStd := func(path string) bool { return !strings.Contains(strings.Split(path, "/")[0], ".") }
+		sort.Slice(paths, func(i, j int) bool {
+			if isStd(paths[i]) != isStd(paths[j]) {
+				return isStd(paths[i])
+			}
+			return paths[i] < paths[j]
+		})
+		b.WriteString("import (\n")
+		for i, path := range paths {
+			if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+				b.WriteString("\n")
+			}
+			if name := imports[path]; name != filepath.Base(path) {
+				fmt.Fprintf(&b, "\t%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&b, "\t%q\n", path)
+			}
+		}
+		b.WriteString(")\n\n")
+	}
+
+	for _, a := range accessors {
+		fmt.Fprintf(&b, "// %s возвращает %s.%s.\n", a.Name, a.Struct, a.Field)
+		fmt.Fprintf(&b, "func %s(v %s) %s { return v.%s }\n\n", a.Name, a.Struct, a.Type, a.Field)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package models
+
+import (
+	"time"
+
+	dec "github.com/shopspring/decimal"
+)
+
+type User struct {
+	ID        int64     ` + "`db:\"id\" ext:\"\"`" + `
+	Email     string    ` + "`ext:\"UserEmail\"`" + `
+	CreatedAt time.Time ` + "`ext:\"\"`" + `
+	Balance   dec.Decimal ` + "`ext:\"\"`" + `
+	Password  string    ` + "`ext:\"-\"`" + `
+	Name      string
+}
+
+type Order struct {
+	Tags []string ` + "`ext:\"\"`" + `
+}
+`
+
+func writePackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"models.go":      testSource,
+		"models_test.go": "package models\n\ntype Fake struct{ X int `ext:\"\"` }\n",
+		defaultOutput:    "package models\n\nfunc Old() {}\n",
+	})
+
+	src, err := generate(dir, nil, defaultOutput)
+	if err != nil {
+		t.Fatalf("generate вернула ошибку: %v", err)
+	}
+	code := string(src)
+	for _, expected := range []string{
+		"// Code generated by extgen; DO NOT EDIT.",
+		"package models",
+		`"time"`,
+		`dec "github.com/shopspring/decimal"`,
+		"func UserID(v User) int64 { return v.ID }",
+		"func UserEmail(v User) string { return v.Email }",
+		"func UserCreatedAt(v User) time.Time { return v.CreatedAt }",
+		"func UserBalance(v User) dec.Decimal { return v.Balance }",
+		"func OrderTags(v Order) []string { return v.Tags }",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Ожидалось %q в сгенерированном коде:\n%s", expected, code)
+		}
+	}
+	for _, unexpected := range []string{"Password", "UserName", "Fake", "Old"} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("Не ожидалось %q в сгенерированном коде:\n%s", unexpected, code)
+		}
+	}
+
+	src, err = generate(dir, []string{"Order"}, defaultOutput)
+	if err != nil || strings.Contains(string(src), "UserID") || strings.Contains(string(src), `"time"`) {
+		t.Errorf("Флаг -type должен ограничивать структуры: %v\n%s", err, src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := map[string]string{
+		"дублирующийся аксессор": "package m\n\ntype A struct{ X int `ext:\"Get\"` }\ntype B struct{ Y int `ext:\"Get\"` }\n",
+		"неэкспортируемое поле":  "package m\n\ntype A struct{ x int `ext:\"\"` }\n",
+		"нет тегов":              "package m\n\ntype A struct{ X int }\n",
+	}
+	for name, src := range cases {
+		dir := writePackage(t, map[string]string{"m.go": src})
+		if _, err := generate(dir, nil, defaultOutput); err == nil {
+			t.Errorf("%s: ожидалась ошибка", name)
+		}
+	}
+}
+
+func TestDefaultImportName(t *testing.T) {
+	cases := map[string]string{
+		"time":                         "time",
+		"github.com/jackc/pgx/v5":      "pgx",
+		"gopkg.in/yaml.v3":             "yaml",
+		"github.com/redis/go-redis/v9": "redis",
+	}
+	for path, expected := range cases {
+		if name := defaultImportName(path); name != expected {
+			t.Errorf("%s: ожидалось %s, а получили %s", path, expected, name)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Команда extgen генерирует типизированные аксессоры для полей структур с тегом ext,
+// чтобы передавать их в ext.Pluck вместо имени поля в ext.ExtractField.
+//
+//	type User struct {
+//		ID    int64  `ext:""`          // func UserID(v User) int64
+//		Email string `ext:"UserEmail"` // имя аксессора задаётся значением тега
+//	}
+//
+//	//go:generate go run github.com/seemyown/backend-toolkit/btools/ext/cmd/extgen
+//
+//	ids := ext.Pluck(users, UserID)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	types := flag.String("type", "", "comma separated struct names (default all with ext tags)")
+	output := flag.String("output", defaultOutput, "output file name")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var names []string
+	if *types != "" {
+		names = strings.Split(*types, ",")
+	}
+	src, err := generate(dir, names, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "extgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "extgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,155 @@
+package ext
+
+// Pair - пара значений, результат Zip.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Map применяет f к каждому элементу.
+func Map[T, R any](items []T, f func(T) R) []R {
+	if items == nil {
+		return nil
+	}
+	result := make([]R, len(items))
+	for i, item := range items {
+		result[i] = f(item)
+	}
+	return result
+}
+
+// Filter возвращает элементы, для которых keep вернула true, сохраняя порядок.
+func Filter[T any](items []T, keep func(T) bool) []T {
+	var result []T
+	for _, item := range items {
+		if keep(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Reduce сворачивает элементы слева направо, начиная с initial.
+func Reduce[T, R any](items []T, initial R, f func(acc R, item T) R) R {
+	acc := initial
+	for _, item := range items {
+		acc = f(acc, item)
+	}
+	return acc
+}
+
+// GroupBy раскладывает элементы по ключу, порядок внутри группы сохраняется.
+func GroupBy[T any, K comparable](items []T, key func(T) K) map[K][]T {
+	result := make(map[K][]T)
+	for _, item := range items {
+		k := key(item)
+		result[k] = append(result[k], item)
+	}
+	return result
+}
+
+// KeyBy строит индекс по ключу; при совпадении ключей остаётся последний элемент.
+func KeyBy[T any, K comparable](items []T, key func(T) K) map[K]T {
+	result := make(map[K]T, len(items))
+	for _, item := range items {
+		result[key(item)] = item
+	}
+	return result
+}
+
+// Chunk делит слайс на части по size элементов (последняя может быть короче).
+// Части ссылаются на исходный массив без копирования, но append к части его не испортит.
+func Chunk[T any](items []T, size int) [][]T {
+	if size <= 0 {
+		panic("ext.Chunk: size must be positive")
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	result := make([][]T, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items); start += size {
+		end := min(start+size, len(items))
+		result = append(result, items[start:end:end])
+	}
+	return result
+}
+
+// Partition делит элементы на подходящие под условие и остальные.
+func Partition[T any](items []T, match func(T) bool) (matched, rest []T) {
+	for _, item := range items {
+		if match(item) {
+			matched = append(matched, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+	return matched, rest
+}
+
+// Uniq убирает повторы, оставляя первое вхождение.
+func Uniq[T comparable](items []T) []T {
+	return UniqBy(items, func(item T) T { return item })
+}
+
+// UniqBy убирает элементы с повторяющимся ключом, оставляя первое вхождение.
+func UniqBy[T any, K comparable](items []T, key func(T) K) []T {
+	if items == nil {
+		return nil
+	}
+	seen := make(map[K]struct{}, len(items))
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		k := key(item)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		result = append(result, item)
+	}
+	return result
+}
+
+// Intersect возвращает элементы a, которые есть в b, без повторов и в порядке a.
+func Intersect[T comparable](a, b []T) []T {
+	set := make(map[T]struct{}, len(b))
+	for _, v := range b {
+		set[v] = struct{}{}
+	}
+	var result []T
+	for _, v := range a {
+		if _, ok := set[v]; ok {
+			result = append(result, v)
+			delete(set, v)
+		}
+	}
+	return result
+}
+
+// Flatten склеивает вложенные слайсы в один за одно выделение памяти.
+func Flatten[T any](lists [][]T) []T {
+	total := 0
+	for _, list := range lists {
+		total += len(list)
+	}
+	if total == 0 {
+		return nil
+	}
+	result := make([]T, 0, total)
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+	return result
+}
+
+// Zip составляет пары из элементов с одинаковым индексом; лишние элементы длинного слайса отбрасываются.
+func Zip[A, B any](a []A, b []B) []Pair[A, B] {
+	n := min(len(a), len(b))
+	if n == 0 {
+		return nil
+	}
+	result := make([]Pair[A, B], n)
+	for i := range n {
+		result[i] = Pair[A, B]{First: a[i], Second: b[i]}
+	}
+	return result
+}
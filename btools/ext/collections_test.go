@@ -0,0 +1,189 @@
+package ext
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestUnionKeepsOrder(t *testing.T) {
+	result := Union([]int{3, 1, 3, 2}, []int{2, 5, 1, 4})
+	expected := []int{3, 1, 2, 5, 4}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestMapFilterReduce(t *testing.T) {
+	nums := []int{1, 2, 3, 4, 5}
+
+	strs := Map(nums, strconv.Itoa)
+	if !reflect.DeepEqual(strs, []string{"1", "2", "3", "4", "5"}) {
+		t.Errorf("Unexpected Map result %v", strs)
+	}
+	if Map[int, string](nil, strconv.Itoa) != nil {
+		t.Errorf("Expected nil for nil slice")
+	}
+
+	even := Filter(nums, func(n int) bool { return n%2 == 0 })
+	if !reflect.DeepEqual(even, []int{2, 4}) {
+		t.Errorf("Unexpected Filter result %v", even)
+	}
+
+	sum := Reduce(nums, 0, func(acc, n int) int { return acc + n })
+	if sum != 15 {
+		t.Errorf("Expected 15, got %d", sum)
+	}
+}
+
+func TestGroupByKeyBy(t *testing.T) {
+	persons := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}, {Name: "Carol", Age: 30}}
+
+	byAge := GroupBy(persons, func(p Person) int { return p.Age })
+	if len(byAge) != 2 || len(byAge[30]) != 2 || byAge[30][1].Name != "Carol" {
+		t.Errorf("Unexpected GroupBy result %v", byAge)
+	}
+
+	byName := KeyBy(persons, func(p Person) string { return p.Name })
+	if byName["Bob"].Age != 25 || len(byName) != 3 {
+		t.Errorf("Unexpected KeyBy result %v", byName)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	chunks := Chunk(items, 2)
+	if !reflect.DeepEqual(chunks, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Errorf("Unexpected Chunk result %v", chunks)
+	}
+
+	// append к части не должен затирать следующую часть
+	_ = append(chunks[0], 100)
+	if items[2] != 3 {
+		t.Errorf("Appending to a chunk overwrote the source slice: %v", items)
+	}
+
+	if Chunk([]int{}, 3) != nil {
+		t.Errorf("Expected nil for empty slice")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for non-positive size")
+		}
+	}()
+	Chunk(items, 0)
+}
+
+func TestPartition(t *testing.T) {
+	matched, rest := Partition([]int{1, 2, 3, 4, 5}, func(n int) bool { return n > 3 })
+	if !reflect.DeepEqual(matched, []int{4, 5}) || !reflect.DeepEqual(rest, []int{1, 2, 3}) {
+		t.Errorf("Unexpected Partition result %v / %v", matched, rest)
+	}
+}
+
+func TestUniq(t *testing.T) {
+	if result := Uniq([]int{3, 1, 3, 2, 1}); !reflect.DeepEqual(result, []int{3, 1, 2}) {
+		t.Errorf("Unexpected Uniq result %v", result)
+	}
+
+	persons := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}, {Name: "Carol", Age: 30}}
+	result := UniqBy(persons, func(p Person) int { return p.Age })
+	if len(result) != 2 || result[0].Name != "Alice" || result[1].Name != "Bob" {
+		t.Errorf("Unexpected UniqBy result %v", result)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	result := Intersect([]int{5, 1, 2, 2, 3}, []int{2, 3, 4, 5})
+	if !reflect.DeepEqual(result, []int{5, 2, 3}) {
+		t.Errorf("Unexpected Intersect result %v", result)
+	}
+	if Intersect([]int{1}, nil) != nil {
+		t.Errorf("Expected nil for empty intersection")
+	}
+}
+
+func TestFlattenZip(t *testing.T) {
+	flat := Flatten([][]int{{1, 2}, nil, {3}})
+	if !reflect.DeepEqual(flat, []int{1, 2, 3}) || cap(flat) != 3 {
+		t.Errorf("Unexpected Flatten result %v (cap %d)", flat, cap(flat))
+	}
+
+	pairs := Zip([]string{"a", "b", "c"}, []int{1, 2})
+	expected := []Pair[string, int]{{"a", 1}, {"b", 2}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+}
+
+func benchmarkInts(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i % (n / 2)
+	}
+	return items
+}
+
+func BenchmarkMap(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Map(items, func(n int) int { return n * 2 })
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Filter(items, func(n int) bool { return n%2 == 0 })
+	}
+}
+
+func BenchmarkGroupBy(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = GroupBy(items, func(n int) int { return n % 16 })
+	}
+}
+
+func BenchmarkChunk(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Chunk(items, 100)
+	}
+}
+
+func BenchmarkUniq(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Uniq(items)
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	a, c := benchmarkInts(10_000), benchmarkInts(5_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Union(a, c)
+	}
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	a, c := benchmarkInts(10_000), benchmarkInts(5_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Intersect(a, c)
+	}
+}
+
+func BenchmarkFlatten(b *testing.B) {
+	lists := Chunk(benchmarkInts(10_000), 100)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Flatten(lists)
+	}
+}
@@ -1,6 +1,17 @@
 package ext
 
-import "reflect"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrUnknownField - у структуры нет экспортируемого поля с таким именем.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrFieldType - тип поля не совпадает с запрошенным.
+	ErrFieldType = errors.New("wrong field type")
+)
 
 func Contains[T comparable](slice []T, item T) bool {
 	if slice == nil {
@@ -22,44 +33,58 @@ func Ternary[T any](condition bool, a T, b T) T {
 	}
 }
 
+// Union возвращает элементы a и b без повторов в порядке первого появления.
 func Union[T comparable](a, b []T) []T {
-	uniqueMap := make(map[T]struct{})
-	var result []T
-
-	for _, val := range a {
-		uniqueMap[val] = struct{}{}
-	}
+	seen := make(map[T]struct{}, len(a)+len(b))
+	result := make([]T, 0, len(a)+len(b))
 
-	for _, val := range b {
-		uniqueMap[val] = struct{}{}
-	}
-
-	for key := range uniqueMap {
-		result = append(result, key)
+	for _, list := range [][]T{a, b} {
+		for _, val := range list {
+			if _, ok := seen[val]; ok {
+				continue
+			}
+			seen[val] = struct{}{}
+			result = append(result, val)
+		}
 	}
 
 	return result
 }
 
-func ExtractField[T any, V any](items []T, fieldName string) []V {
-	var result []V
-	for _, item := range items {
+// ExtractField возвращает значения поля fieldName у элементов (структур или указателей на них).
+// Неизвестное или неэкспортируемое поле, поле другого типа и nil-элемент - ошибка.
+// Поле ищется через reflect по имени; если поле известно при компиляции, используйте Pluck.
+func ExtractField[T any, V any](items []T, fieldName string) ([]V, error) {
+	result := make([]V, 0, len(items))
+	for i, item := range items {
 		val := reflect.ValueOf(item)
 
 		// Разыменовываем указатель, если это нужно
 		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return nil, fmt.Errorf("ext.ExtractField: item %d is nil", i)
+			}
 			val = val.Elem()
 		}
+		if val.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("ext.ExtractField: item %d is %s, not a struct", i, val.Kind())
+		}
 
 		// Проверяем, есть ли поле
 		field := val.FieldByName(fieldName)
-		if field.IsValid() && field.CanInterface() {
-			if v, ok := field.Interface().(V); ok {
-				result = append(result, v)
-			}
+		if !field.IsValid() {
+			return nil, fmt.Errorf("%w: %s.%s", ErrUnknownField, val.Type(), fieldName)
 		}
+		if !field.CanInterface() {
+			return nil, fmt.Errorf("%w: %s.%s is unexported", ErrUnknownField, val.Type(), fieldName)
+		}
+		v, ok := field.Interface().(V)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s is %s, not %s", ErrFieldType, val.Type(), fieldName, field.Type(), reflect.TypeFor[V]())
+		}
+		result = append(result, v)
 	}
-	return result
+	return result, nil
 }
 
 func Diff[T comparable](slice1, slice2 []T) []T {
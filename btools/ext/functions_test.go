@@ -1,6 +1,7 @@
 package ext
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -66,7 +67,10 @@ func TestExtractField(t *testing.T) {
 		{Name: "Alice", Age: 30},
 		{Name: "Bob", Age: 25},
 	}
-	names := ExtractField[Person, string](persons, "Name")
+	names, err := ExtractField[Person, string](persons, "Name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	expectedNames := []string{"Alice", "Bob"}
 	if !reflect.DeepEqual(names, expectedNames) {
 		t.Errorf("Expected %v, got %v", expectedNames, names)
@@ -77,16 +81,28 @@ func TestExtractField(t *testing.T) {
 		{Name: "Charlie", Age: 28},
 		{Name: "Diana", Age: 32},
 	}
-	namesPtr := ExtractField[*Person, string](personsPtr, "Name")
+	namesPtr, err := ExtractField[*Person, string](personsPtr, "Name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	expectedNamesPtr := []string{"Charlie", "Diana"}
 	if !reflect.DeepEqual(namesPtr, expectedNamesPtr) {
 		t.Errorf("Expected %v, got %v", expectedNamesPtr, namesPtr)
 	}
 
-	// Тест для несуществующего поля: должно вернуть пустой слайс
-	result := ExtractField[Person, string](persons, "NonExistent")
-	if len(result) != 0 {
-		t.Errorf("Expected empty slice for non-existent field, got %v", result)
+	// Тест для несуществующего поля: должна вернуться ошибка
+	if _, err := ExtractField[Person, string](persons, "NonExistent"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField for non-existent field, got %v", err)
+	}
+
+	// Тест для поля другого типа: должна вернуться ошибка, а не пустой слайс
+	if _, err := ExtractField[Person, string](persons, "Age"); !errors.Is(err, ErrFieldType) {
+		t.Errorf("Expected ErrFieldType for int field, got %v", err)
+	}
+
+	// Тест для nil-указателя в слайсе
+	if _, err := ExtractField[*Person, string]([]*Person{nil}, "Name"); err == nil {
+		t.Errorf("Expected error for nil item")
 	}
 }
 
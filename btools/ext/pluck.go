@@ -0,0 +1,47 @@
+package ext
+
+import "iter"
+
+// Pluck возвращает значение, полученное через get, для каждого элемента. В отличие от ExtractField
+// не использует reflect: поле и его тип проверяет компилятор. Аксессоры для get можно сгенерировать
+// командой extgen, см. ext/cmd/extgen.
+//
+//	ids := ext.Pluck(users, func(u User) int64 { return u.ID })
+func Pluck[T, V any](items []T, get func(T) V) []V {
+	return Map(items, get)
+}
+
+// PluckUniq - Pluck без повторяющихся значений, в порядке первого появления.
+func PluckUniq[T any, V comparable](items []T, get func(T) V) []V {
+	if items == nil {
+		return nil
+	}
+	seen := make(map[V]struct{}, len(items))
+	result := make([]V, 0, len(items))
+	for _, item := range items {
+		v := get(item)
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
+
+// PluckNonZero - Pluck без нулевых значений (пустых строк, 0, nil).
+func PluckNonZero[T any, V comparable](items []T, get func(T) V) []V {
+	var zero V
+	var result []V
+	for _, item := range items {
+		if v := get(item); v != zero {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// PluckSeq - ленивый Pluck.
+func PluckSeq[T, V any](seq iter.Seq[T], get func(T) V) iter.Seq[V] {
+	return MapSeq(seq, get)
+}
@@ -0,0 +1,42 @@
+package ext
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPluck(t *testing.T) {
+	persons := []Person{{Name: "Alice", Age: 30}, {Name: "", Age: 25}, {Name: "Alice", Age: 30}}
+	name := func(p Person) string { return p.Name }
+
+	if names := Pluck(persons, name); !reflect.DeepEqual(names, []string{"Alice", "", "Alice"}) {
+		t.Errorf("Unexpected Pluck result %v", names)
+	}
+	if names := PluckUniq(persons, name); !reflect.DeepEqual(names, []string{"Alice", ""}) {
+		t.Errorf("Unexpected PluckUniq result %v", names)
+	}
+	if names := PluckNonZero(persons, name); !reflect.DeepEqual(names, []string{"Alice", "Alice"}) {
+		t.Errorf("Unexpected PluckNonZero result %v", names)
+	}
+	ages := slices.Collect(PluckSeq(slices.Values(persons), func(p Person) int { return p.Age }))
+	if !reflect.DeepEqual(ages, []int{30, 25, 30}) {
+		t.Errorf("Unexpected PluckSeq result %v", ages)
+	}
+}
+
+func BenchmarkPluck(b *testing.B) {
+	persons := make([]Person, 10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = Pluck(persons, func(p Person) string { return p.Name })
+	}
+}
+
+func BenchmarkExtractField(b *testing.B) {
+	persons := make([]Person, 10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = ExtractField[Person, string](persons, "Name")
+	}
+}
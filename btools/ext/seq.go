@@ -0,0 +1,108 @@
+package ext
+
+import "iter"
+
+// Ленивые варианты функций пакета для iter.Seq: элементы обрабатываются по одному,
+// промежуточные слайсы не создаются. Результат собирается через slices.Collect.
+
+// MapSeq - ленивый Map.
+func MapSeq[T, R any](seq iter.Seq[T], f func(T) R) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for item := range seq {
+			if !yield(f(item)) {
+				return
+			}
+		}
+	}
+}
+
+// FilterSeq - ленивый Filter.
+func FilterSeq[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range seq {
+			if keep(item) && !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ReduceSeq сворачивает последовательность, начиная с initial.
+func ReduceSeq[T, R any](seq iter.Seq[T], initial R, f func(acc R, item T) R) R {
+	acc := initial
+	for item := range seq {
+		acc = f(acc, item)
+	}
+	return acc
+}
+
+// UniqSeq пропускает повторы, оставляя первое вхождение.
+func UniqSeq[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+	return UniqBySeq(seq, func(item T) T { return item })
+}
+
+// UniqBySeq пропускает элементы с повторяющимся ключом.
+func UniqBySeq[T any, K comparable](seq iter.Seq[T], key func(T) K) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		seen := make(map[K]struct{})
+		for item := range seq {
+			k := key(item)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ChunkSeq группирует элементы в слайсы по size. Каждый слайс - новый, его можно сохранять.
+func ChunkSeq[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	if size <= 0 {
+		panic("ext.ChunkSeq: size must be positive")
+	}
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, size)
+		for item := range seq {
+			chunk = append(chunk, item)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// FlattenSeq разворачивает последовательность слайсов.
+func FlattenSeq[T any](seq iter.Seq[[]T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for list := range seq {
+			for _, item := range list {
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ZipSeq составляет пары из двух последовательностей, пока не закончится более короткая.
+func ZipSeq[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq[Pair[A, B]] {
+	return func(yield func(Pair[A, B]) bool) {
+		nextB, stop := iter.Pull(b)
+		defer stop()
+		for first := range a {
+			second, ok := nextB()
+			if !ok || !yield(Pair[A, B]{First: first, Second: second}) {
+				return
+			}
+		}
+	}
+}
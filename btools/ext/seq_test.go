@@ -0,0 +1,87 @@
+package ext
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSeqPipeline(t *testing.T) {
+	seq := slices.Values([]int{1, 2, 2, 3, 4, 4, 5, 6})
+	doubled := MapSeq(FilterSeq(UniqSeq(seq), func(n int) bool { return n%2 == 0 }), func(n int) int { return n * 10 })
+	if result := slices.Collect(doubled); !reflect.DeepEqual(result, []int{20, 40, 60}) {
+		t.Errorf("Unexpected pipeline result %v", result)
+	}
+
+	sum := ReduceSeq(slices.Values([]int{1, 2, 3}), 0, func(acc, n int) int { return acc + n })
+	if sum != 6 {
+		t.Errorf("Expected 6, got %d", sum)
+	}
+}
+
+func TestSeqEarlyStop(t *testing.T) {
+	pulled := 0
+	source := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			pulled++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	var first []int
+	for n := range MapSeq(source, func(n int) int { return n + 1 }) {
+		first = append(first, n)
+		if len(first) == 3 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(first, []int{1, 2, 3}) || pulled != 3 {
+		t.Errorf("Expected lazy evaluation, got %v after %d pulls", first, pulled)
+	}
+}
+
+func TestChunkFlattenSeq(t *testing.T) {
+	chunks := slices.Collect(ChunkSeq(slices.Values([]int{1, 2, 3, 4, 5}), 2))
+	if !reflect.DeepEqual(chunks, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Errorf("Unexpected ChunkSeq result %v", chunks)
+	}
+
+	flat := slices.Collect(FlattenSeq(slices.Values(chunks)))
+	if !reflect.DeepEqual(flat, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Unexpected FlattenSeq result %v", flat)
+	}
+}
+
+func TestZipSeq(t *testing.T) {
+	pairs := slices.Collect(ZipSeq(slices.Values([]string{"a", "b", "c"}), slices.Values([]int{1, 2})))
+	expected := []Pair[string, int]{{"a", 1}, {"b", 2}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+
+	persons := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 30}}
+	uniq := slices.Collect(UniqBySeq(slices.Values(persons), func(p Person) int { return p.Age }))
+	if len(uniq) != 1 || uniq[0].Name != "Alice" {
+		t.Errorf("Unexpected UniqBySeq result %v", uniq)
+	}
+}
+
+func BenchmarkMapFilterSeq(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		seq := FilterSeq(MapSeq(slices.Values(items), func(n int) int { return n * 2 }), func(n int) bool { return n%3 == 0 })
+		_ = ReduceSeq(seq, 0, func(acc, n int) int { return acc + n })
+	}
+}
+
+func BenchmarkMapFilterSlice(b *testing.B) {
+	items := benchmarkInts(10_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		filtered := Filter(Map(items, func(n int) int { return n * 2 }), func(n int) bool { return n%3 == 0 })
+		_ = Reduce(filtered, 0, func(acc, n int) int { return acc + n })
+	}
+}
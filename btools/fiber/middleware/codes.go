@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"embed"
+	"net/http"
+
+	"github.com/seemyown/backend-toolkit/btools/exc"
+)
+
+// locales - сообщения для кодов middleware, загружаются в exc.DefaultCatalog.
+//
+//go:embed locales
+var locales embed.FS
+
+// Коды ошибок JWTMiddleware и APIKeyMiddleware.
+func init() {
+	if err := exc.DefaultCatalog.Load(locales, "locales"); err != nil {
+		panic(err)
+	}
+	exc.MustRegister(
+		exc.CodeDefinition{Code: "bad_token", Status: http.StatusForbidden, Description: "Authorization header is malformed"},
+		exc.CodeDefinition{Code: "invalid_token", Status: http.StatusForbidden, Description: "Token signature or claims are invalid"},
+		exc.CodeDefinition{Code: "token_expired", Status: http.StatusUnauthorized, Description: "Token has expired, obtain a new one"},
+		exc.CodeDefinition{Code: "wrong_issuer", Status: http.StatusForbidden, Description: "Token was issued by an unexpected issuer"},
+		exc.CodeDefinition{Code: "missing_api_key", Status: http.StatusForbidden, Description: "API key header is missing"},
+		exc.CodeDefinition{Code: "invalid_api_key", Status: http.StatusForbidden, Description: "API key does not match"},
+	)
+}
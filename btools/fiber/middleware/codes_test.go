@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/seemyown/backend-toolkit/btools/exc"
+)
+
+func TestCodes(t *testing.T) {
+	// коды middleware и db берут сообщения из своих locales
+	if err := exc.Validate(); err != nil {
+		t.Errorf("Все объявленные коды должны иметь сообщение по умолчанию: %v", err)
+	}
+	if msg, _ := exc.DefaultCatalog.Lookup("en", "token_expired"); msg != "Token is expired" {
+		t.Errorf("Ожидалось сообщение из locales middleware, а получили %q", msg)
+	}
+	if err := exc.FromCode("invalid_api_key"); err.StatusCode != 403 || err.Message != "Неверный API-ключ" {
+		t.Errorf("Неверная ошибка по коду: %+v", err)
+	}
+}
@@ -5,44 +5,113 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/seemyown/backend-toolkit/btools/db"
 	"github.com/seemyown/backend-toolkit/btools/exc"
+	"regexp"
 	"strings"
 )
 
 var errLogger = log.NewSubLogger("error_middleware")
 
+var safeFieldRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.]{0,62}$`)
+
+// ErrorFormat - формат тела ответа с ошибкой.
+type ErrorFormat int
+
+const (
+	// ErrorFormatLegacy - exc.Error как есть (формат по умолчанию).
+	ErrorFormatLegacy ErrorFormat = iota
+	// ErrorFormatProblem - application/problem+json по RFC 7807, см. exc.Problem.
+	ErrorFormatProblem
+)
+
+type ErrorMiddlewareConfig struct {
+	// Locale - язык сообщений, если клиент не прислал Accept-Language или ни один из его
+	// языков не поддерживается exc.DefaultCatalog. По умолчанию - язык каталога по умолчанию.
+	Locale string
+	// ExposeFields - отдавать клиенту поле, вызвавшее ошибку валидации (нарушение
+	// уникальности, внешнего ключа, NOT NULL и т.п.)
+	ExposeFields bool
+	// FieldNameMapper переводит имя колонки в имя поля API. Пустая строка скрывает поле.
+	FieldNameMapper func(table, column string) string
+	// Format - формат ответа
+	Format ErrorFormat
+	// ProblemTypeBaseURL - префикс для поля type в ErrorFormatProblem, к нему дописывается код ошибки
+	// (например, "https://api.example.com/errors/"). Без него type = "about:blank".
+	ProblemTypeBaseURL string
+}
+
 func ErrorMiddleware(locale string) fiber.Handler {
+	return ErrorMiddlewareWithConfig(ErrorMiddlewareConfig{Locale: locale})
+}
+
+func ErrorMiddlewareWithConfig(config ErrorMiddlewareConfig) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		err := ctx.Next()
 		if err == nil {
 			return nil
 		}
 
+		locale := config.locale(ctx)
 		var appErr *exc.Error
 		var fiberErr *fiber.Error
 		var repositoryErr *db.RepositoryError
-		if errors.As(err, &fiberErr) {
+		switch {
+		case errors.As(err, &fiberErr):
 			appErr = exc.NewAppError(
 				strings.TrimSpace(fiberErr.Message),
 				toSnakeCase(fiberErr.Message),
 				"",
 				fiberErr.Code,
 			)
-		} else if errors.As(err, &repositoryErr) {
-			appErr = exc.NewAppError(
-				"InternalError",
-				"repository_error",
-				repositoryErr.MessageFor(locale),
-				db.MapToHttpError[repositoryErr.Code],
-			)
-		} else if !errors.As(err, &appErr) {
-			appErr = exc.InternalServerError("Unknown error")
+		case errors.As(err, &appErr):
+			// ответ уже собран обработчиком, даже если внутри обёрнут RepositoryError
+		case errors.As(err, &repositoryErr):
+			appErr = repositoryErr.AppError(locale)
+			if config.ExposeFields && repositoryErr.IsValidation() {
+				appErr.Field = config.fieldName(repositoryErr)
+			}
+		case db.IsNotFound(err):
+			appErr = exc.ErrNotFound.WithCause(err)
+		default:
+			appErr = exc.InternalServerError("").WithCause(err)
 		}
+		appErr = appErr.Localize(locale)
 
-		errLogger.Error(err, "Request error %+v", appErr)
+		if stack := appErr.StackTrace(); stack != "" {
+			errLogger.Error(err, "Request error request_id=%s %+v\n%s", requestID(ctx), appErr, stack)
+		} else {
+			errLogger.Error(err, "Request error request_id=%s %+v", requestID(ctx), appErr)
+		}
+		if config.Format == ErrorFormatProblem {
+			problem := appErr.Problem(config.ProblemTypeBaseURL, ctx.Path())
+			return ctx.Status(appErr.StatusCode).JSON(problem, exc.ProblemContentType)
+		}
 		return ctx.Status(appErr.StatusCode).JSON(appErr)
 	}
 }
 
+// locale выбирает язык ответа по Accept-Language.
+func (c ErrorMiddlewareConfig) locale(ctx *fiber.Ctx) string {
+	if lang, ok := exc.DefaultCatalog.Match(ctx.Get(fiber.HeaderAcceptLanguage)); ok {
+		return lang
+	}
+	if c.Locale != "" {
+		return c.Locale
+	}
+	return exc.DefaultCatalog.Fallback()
+}
+
+// fieldName возвращает безопасное для ответа имя поля или пустую строку.
+func (c ErrorMiddlewareConfig) fieldName(err *db.RepositoryError) string {
+	field := err.Field
+	if c.FieldNameMapper != nil {
+		field = c.FieldNameMapper(err.Table, field)
+	}
+	if !safeFieldRe.MatchString(field) {
+		return ""
+	}
+	return field
+}
+
 func toSnakeCase(s string) string {
 	return strings.ToLower(strings.ReplaceAll(s, " ", "_"))
 }
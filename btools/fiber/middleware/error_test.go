@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/seemyown/backend-toolkit/btools/exc"
+)
+
+func newErrorApp(config ErrorMiddlewareConfig, err error) *fiber.App {
+	app := fiber.New()
+	app.Use(ErrorMiddlewareWithConfig(config))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) error {
+		return err
+	})
+	return app
+}
+
+func TestErrorMiddleware_Problem(t *testing.T) {
+	appErr := exc.ValidationErrors("validation_failed", "Проверьте поля",
+		exc.FieldViolation{Field: "email", Code: "required", Message: "Обязательное поле"},
+	)
+	app := newErrorApp(ErrorMiddlewareConfig{
+		Format:             ErrorFormatProblem,
+		ProblemTypeBaseURL: "https://api.example.com/errors/",
+	}, appErr)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/users/42", nil))
+	if err != nil {
+		t.Fatalf("Запрос завершился ошибкой: %v", err)
+	}
+	if resp.StatusCode != 422 {
+		t.Errorf("Ожидался статус 422, а получили %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != exc.ProblemContentType {
+		t.Errorf("Ожидался Content-Type %s, а получили %s", exc.ProblemContentType, ct)
+	}
+
+	var problem exc.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("Не удалось разобрать ответ: %v", err)
+	}
+	if problem.Type != "https://api.example.com/errors/validation_failed" || problem.Title != "Unprocessable Entity" ||
+		problem.Detail != "Проверьте поля" || problem.Instance != "/users/42" || problem.Status != 422 {
+		t.Errorf("Неверное тело problem+json: %+v", problem)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "email" {
+		t.Errorf("Ожидались ошибки по полям, а получили %+v", problem.Errors)
+	}
+}
+
+func TestErrorMiddleware_Legacy(t *testing.T) {
+	app := newErrorApp(ErrorMiddlewareConfig{}, exc.NotFoundError("user_not_found", "Пользователь не найден"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/users/42", nil))
+	if err != nil {
+		t.Fatalf("Запрос завершился ошибкой: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != fiber.MIMEApplicationJSON {
+		t.Errorf("Ожидался Content-Type %s, а получили %s", fiber.MIMEApplicationJSON, ct)
+	}
+	var body exc.Error
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Не удалось разобрать ответ: %v", err)
+	}
+	if resp.StatusCode != 404 || body.Code != "user_not_found" {
+		t.Errorf("Неверный ответ: %d %+v", resp.StatusCode, body)
+	}
+}
+
+func TestErrorMiddleware_AcceptLanguage(t *testing.T) {
+	app := newErrorApp(ErrorMiddlewareConfig{Locale: "ru"}, exc.NotFoundError("not_found", ""))
+
+	cases := map[string]string{
+		"":                          "Запись не найдена",
+		"en-US,en;q=0.9":            "Record not found",
+		"de-DE, en;q=0.5, ru;q=0.7": "Запись не найдена",
+		"fr":                        "Запись не найдена",
+	}
+	for header, expected := range cases {
+		req := httptest.NewRequest("GET", "/users/42", nil)
+		if header != "" {
+			req.Header.Set("Accept-Language", header)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Запрос завершился ошибкой: %v", err)
+		}
+		var body exc.Error
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		if body.Message != expected {
+			t.Errorf("Accept-Language %q: ожидалось %q, а получили %q", header, expected, body.Message)
+		}
+	}
+}
+
+func TestErrorMiddleware_NoRows(t *testing.T) {
+	app := newErrorApp(ErrorMiddlewareConfig{}, fmt.Errorf("get user: %w", sql.ErrNoRows))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/users/42", nil))
+	if err != nil {
+		t.Fatalf("Запрос завершился ошибкой: %v", err)
+	}
+	var body exc.Error
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != 404 || body.Code != "not_found" {
+		t.Errorf("sql.ErrNoRows должна отдаваться как 404, а получили %d %+v", resp.StatusCode, body)
+	}
+}
@@ -1,17 +1,37 @@
 package middleware
 
 import (
+	"fmt"
+	"runtime/debug"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/seemyown/backend-toolkit/btools/exc"
 )
 
 var panicMiddlewareLogger = log.NewSubLogger("panic.handler")
 
+type RecoverConfig struct {
+	// StackTrace - писать в лог стек горутины, в которой случилась паника. Клиент стек не получает.
+	StackTrace bool
+}
+
 func RecoverMiddleware() fiber.Handler {
+	return RecoverMiddlewareWithConfig(RecoverConfig{StackTrace: true})
+}
+
+func RecoverMiddlewareWithConfig(config RecoverConfig) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		defer func() {
 			if r := recover(); r != nil {
-				panicMiddlewareLogger.Trace("[PANIC] %v", r)
+				panicErr, ok := r.(error)
+				if !ok {
+					panicErr = fmt.Errorf("%v", r)
+				}
+				if config.StackTrace {
+					panicMiddlewareLogger.Error(panicErr, "[PANIC] request_id=%s %s %s\n%s", requestID(c), c.Method(), c.Path(), debug.Stack())
+				} else {
+					panicMiddlewareLogger.Error(panicErr, "[PANIC] request_id=%s %s %s", requestID(c), c.Method(), c.Path())
+				}
 				err := c.Status(fiber.StatusInternalServerError).JSON(exc.InternalServerError("PANIC"))
 				if err != nil {
 					panicMiddlewareLogger.Error(err, "")
@@ -22,3 +42,17 @@ func RecoverMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// requestIDLocal - ключ Locals, под которым requestid.New из fiber сохраняет идентификатор запроса.
+const requestIDLocal = "requestid"
+
+// requestID возвращает идентификатор запроса из requestid.New или заголовка X-Request-ID.
+func requestID(c *fiber.Ctx) string {
+	if id, ok := c.Locals(requestIDLocal).(string); ok && id != "" {
+		return id
+	}
+	if id := c.GetRespHeader(fiber.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Get(fiber.HeaderXRequestID)
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(RecoverMiddleware())
+	app.Get("/panic", func(ctx *fiber.Ctx) error {
+		var m map[string]int
+		m["boom"] = 1
+		return nil
+	})
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-42")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Запрос завершился ошибкой: %v", err)
+	}
+	if resp.StatusCode != 500 {
+		t.Errorf("Ожидался статус 500, а получили %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "goroutine") || strings.Contains(string(body), "assignment to entry in nil map") {
+		t.Errorf("Стек и текст паники не должны уходить клиенту: %s", body)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		if id := requestID(ctx); id != "from-header" {
+			t.Errorf("Ожидался идентификатор из заголовка, а получили %q", id)
+		}
+		ctx.Locals(requestIDLocal, "from-locals")
+		if id := requestID(ctx); id != "from-locals" {
+			t.Errorf("Ожидался идентификатор из requestid.New, а получили %q", id)
+		}
+		return nil
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "from-header")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("Запрос завершился ошибкой: %v", err)
+	}
+}
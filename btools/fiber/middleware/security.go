@@ -6,6 +6,7 @@ import (
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/seemyown/backend-toolkit/btools/db"
 	"github.com/seemyown/backend-toolkit/btools/exc"
 	"reflect"
 	"strings"
@@ -19,6 +20,11 @@ type JwtMiddlewareConfig struct {
 	TokenType   string
 	Issuer      string
 	Out         interface{}
+	// ActorField - поле Out, значение которого кладётся в UserContext через db.WithActor
+	// и используется репозиториями для колонок created_by/updated_by.
+	ActorField string
+	// TenantField - поле Out с идентификатором арендатора, который кладётся в UserContext через db.WithTenant.
+	TenantField string
 }
 
 func JWTMiddleware(config *JwtMiddlewareConfig) fiber.Handler {
@@ -34,7 +40,7 @@ func JWTMiddleware(config *JwtMiddlewareConfig) fiber.Handler {
 			t, err := extractToken(headerValue)
 			if err != nil {
 				jwtLog.Error(err, "error extracting token")
-				return exc.ForbiddenError("bad_token", err.Error())
+				return exc.ForbiddenError("bad_token", "").WithCause(err)
 			}
 			tokenString = t
 		} else {
@@ -51,32 +57,38 @@ func JWTMiddleware(config *JwtMiddlewareConfig) fiber.Handler {
 		if err != nil || !token.Valid {
 			jwtLog.Error(err, "Incorrect token")
 			if errors.Is(err, jwt.ErrSignatureInvalid) {
-				return exc.ForbiddenError("invalid_token", "wrong token signature")
+				return exc.ForbiddenError("invalid_token", "")
 			} else if "Token is expired" == err.Error() {
-				return exc.UnauthorizedError("token_expired", "token is expired")
+				return exc.UnauthorizedError("token_expired", "")
 			}
 		}
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
 			jwtLog.Error(err, "Incorrect token")
-			return exc.ForbiddenError("invalid_token", "bad claims")
+			return exc.ForbiddenError("invalid_token", "")
 		}
 
 		if claims["iss"] != config.Issuer {
 			jwtLog.Error(errors.New("wrong issuer"), "Incorrect issuer")
-			return exc.ForbiddenError("wrong_issuer", "Wrong token issuer")
+			return exc.ForbiddenError("wrong_issuer", "")
 		}
 
 		mappedData := reflect.New(reflect.TypeOf(config.Out)).Interface()
 		if err := mapstructure.Decode(claims, mappedData); err != nil {
 			jwtLog.Error(err, "Token mapping error")
-			return exc.ForbiddenError("invalid_token", "claims mapping error")
+			return exc.ForbiddenError("invalid_token", "")
 		}
 
 		v := reflect.ValueOf(mappedData).Elem()
 		for i := 0; i < v.NumField(); i++ {
 			field := v.Type().Field(i)
 			ctx.Locals(field.Name, v.Field(i).Interface())
+			if field.Name == config.ActorField {
+				ctx.SetUserContext(db.WithActor(ctx.UserContext(), v.Field(i).Interface()))
+			}
+			if field.Name == config.TenantField {
+				ctx.SetUserContext(db.WithTenant(ctx.UserContext(), fmt.Sprint(v.Field(i).Interface())))
+			}
 		}
 
 		return ctx.Next()
@@ -100,10 +112,10 @@ func APIKeyMiddleware(config *ApiKeyMiddlewareConfig) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get(config.AuthKeyName)
 		if authHeader == "" {
-			return exc.ForbiddenError("missing_api_key", "Missing API key")
+			return exc.ForbiddenError("missing_api_key", "")
 		}
 		if authHeader != config.Secret {
-			return exc.ForbiddenError("invalid_api_key", "Wrong API key")
+			return exc.ForbiddenError("invalid_api_key", "")
 		}
 		jwtLog.Info("Incoming request with %s: %s", config.AuthKeyName, authHeader)
 		return c.Next()
@@ -0,0 +1,77 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/seemyown/backend-toolkit/btools/exc"
+	"github.com/seemyown/backend-toolkit/btools/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+var log = logging.New(logging.Config{
+	FileName: "grpc",
+	Name:     "grpcx",
+})
+
+// metadataAcceptLanguage - ключ метаданных запроса с языком клиента.
+const metadataAcceptLanguage = "accept-language"
+
+type InterceptorConfig struct {
+	// Locale - язык сообщений, если клиент не прислал accept-language в метаданных или ни один
+	// из его языков не поддерживается exc.DefaultCatalog. По умолчанию - язык каталога по умолчанию.
+	Locale string
+}
+
+// UnaryServerInterceptor переводит ошибки обработчиков в статусы gRPC через ToStatus.
+func UnaryServerInterceptor(config InterceptorConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, config.convert(ctx, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor - то же, что UnaryServerInterceptor, для потоковых методов.
+func StreamServerInterceptor(config InterceptorConfig) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return config.convert(ss.Context(), info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+func (c InterceptorConfig) convert(ctx context.Context, method string, err error) error {
+	st := ToStatus(err, c.locale(ctx))
+	var appErr *exc.Error
+	if st.Code() == codes.Internal || st.Code() == codes.Unknown {
+		if errors.As(err, &appErr) && appErr.StackTrace() != "" {
+			log.Error(err, "gRPC %s error %s\n%s", method, st.Message(), appErr.StackTrace())
+		} else {
+			log.Error(err, "gRPC %s error %s", method, st.Message())
+		}
+	} else {
+		log.Warn("gRPC %s error %s: %v", method, st.Code(), err)
+	}
+	return st.Err()
+}
+
+// locale выбирает язык ответа по метаданным accept-language.
+func (c InterceptorConfig) locale(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get(metadataAcceptLanguage) {
+			if lang, ok := exc.DefaultCatalog.Match(value); ok {
+				return lang
+			}
+		}
+	}
+	if c.Locale != "" {
+		return c.Locale
+	}
+	return exc.DefaultCatalog.Fallback()
+}
@@ -0,0 +1,247 @@
+// Package grpcx переводит ошибки exc.Error и db.RepositoryError в статусы gRPC и обратно.
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/seemyown/backend-toolkit/btools/db"
+	"github.com/seemyown/backend-toolkit/btools/exc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Domain - значение ErrorInfo.Domain в статусах, собранных ToStatus.
+var Domain = "btools"
+
+// MetaHTTPStatus - ключ ErrorInfo.Metadata с исходным HTTP-статусом. Нужен, чтобы FromStatus
+// восстановил статус точно: несколько HTTP-статусов соответствуют одному коду gRPC.
+const MetaHTTPStatus = "http_status"
+
+// reasonCodes уточняют код gRPC для ошибок, у которых HTTP-статуса недостаточно.
+var reasonCodes = map[string]codes.Code{
+	"already_exists":          codes.AlreadyExists,
+	"concurrent_modification": codes.Aborted,
+	"deadlock_detected":       codes.Aborted,
+	"serialization_failure":   codes.Aborted,
+}
+
+// CodeFromHTTP возвращает код gRPC для HTTP-статуса.
+func CodeFromHTTP(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return codes.OutOfRange
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable, 523:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 400 && httpStatus < 500 {
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
+
+// HTTPFromCode возвращает HTTP-статус для кода gRPC.
+func HTTPFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ToStatus приводит ошибку к статусу gRPC с сообщением на языке lang (пустой - язык по умолчанию
+// exc.DefaultCatalog). Код ошибки, Meta и исходный HTTP-статус передаются в ErrorInfo, нарушения
+// по полям - в BadRequest, сообщение - ещё и в LocalizedMessage.
+// Ошибки, которые уже являются статусом gRPC, возвращаются как есть, а текст прочих ошибок
+// клиенту не отдаётся - вместо него internal_server_error.
+func ToStatus(err error, lang string) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if lang == "" {
+		lang = exc.DefaultCatalog.Fallback()
+	}
+
+	var appErr *exc.Error
+	var repositoryErr *db.RepositoryError
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &repositoryErr):
+		appErr = repositoryErr.AppError(lang)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err)
+	case db.IsNotFound(err):
+		appErr = exc.ErrNotFound
+	default:
+		if st, ok := status.FromError(err); ok {
+			return st
+		}
+		appErr = exc.InternalServerError("")
+	}
+	return appErrorStatus(appErr.Localize(lang), lang)
+}
+
+func appErrorStatus(appErr *exc.Error, lang string) *status.Status {
+	code, ok := reasonCodes[appErr.Code]
+	if !ok {
+		code = CodeFromHTTP(appErr.StatusCode)
+	}
+	st := status.New(code, appErr.Message)
+
+	info := &errdetails.ErrorInfo{
+		Reason:   appErr.Code,
+		Domain:   Domain,
+		Metadata: map[string]string{MetaHTTPStatus: strconv.Itoa(appErr.StatusCode)},
+	}
+	for key, value := range appErr.Meta {
+		info.Metadata[key] = fmt.Sprint(value)
+	}
+	details := []protoadapt.MessageV1{info}
+
+	violations := appErr.Details
+	if len(violations) == 0 && appErr.Field != "" {
+		violations = []exc.FieldViolation{{Field: appErr.Field, Code: appErr.Code, Message: appErr.Message}}
+	}
+	if len(violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Reason:      v.Code,
+				Description: v.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	if appErr.Message != "" {
+		details = append(details, &errdetails.LocalizedMessage{Locale: lang, Message: appErr.Message})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// FromStatus собирает exc.Error из статуса gRPC, в том числе полученного от сервиса не на btools:
+// без ErrorInfo код ошибки берётся стандартный для HTTP-статуса. Для codes.OK возвращает nil.
+func FromStatus(st *status.Status) *exc.Error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+	httpStatus := HTTPFromCode(st.Code())
+	appErr := &exc.Error{Message: st.Message()}
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			appErr.Code = d.Reason
+			for key, value := range d.Metadata {
+				if key == MetaHTTPStatus {
+					if parsed, err := strconv.Atoi(value); err == nil {
+						httpStatus = parsed
+					}
+					continue
+				}
+				if appErr.Meta == nil {
+					appErr.Meta = make(map[string]any)
+				}
+				appErr.Meta[key] = value
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				appErr.Details = append(appErr.Details, exc.FieldViolation{Field: v.Field, Code: v.Reason, Message: v.Description})
+			}
+		}
+	}
+
+	if appErr.Code == "" {
+		appErr.Code = defaultReason(httpStatus)
+	}
+	appErr.StatusCode = httpStatus
+	appErr.Err = exc.ErrorName(httpStatus)
+	return appErr.WithCause(st.Err())
+}
+
+// FromError - FromStatus для ошибки клиента gRPC. Ошибки, не являющиеся статусом, возвращаются как есть.
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if appErr := FromStatus(st); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func defaultReason(httpStatus int) string {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusServiceUnavailable:
+		return "service_unavailable"
+	default:
+		return "internal_server_error"
+	}
+}
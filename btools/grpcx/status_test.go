@@ -0,0 +1,115 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/seemyown/backend-toolkit/btools/db"
+	"github.com/seemyown/backend-toolkit/btools/exc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	appErr := exc.ValidationErrors("validation_failed", "",
+		exc.FieldViolation{Field: "email", Code: "required"},
+	).WithMeta("limit", 10)
+
+	st := ToStatus(appErr, "en")
+	if st.Code() != codes.InvalidArgument || st.Message() != "Validation failed" {
+		t.Errorf("Ожидался InvalidArgument с переводом, а получили %s %q", st.Code(), st.Message())
+	}
+	var info *errdetails.ErrorInfo
+	var badRequest *errdetails.BadRequest
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			badRequest = d
+		}
+	}
+	if info == nil || info.Reason != "validation_failed" || info.Metadata[MetaHTTPStatus] != "422" || info.Metadata["limit"] != "10" {
+		t.Errorf("Неверный ErrorInfo: %v", info)
+	}
+	if badRequest == nil || badRequest.FieldViolations[0].Field != "email" || badRequest.FieldViolations[0].Description != "Field is required" {
+		t.Errorf("Неверный BadRequest: %v", badRequest)
+	}
+
+	back := FromStatus(st)
+	if back.StatusCode != 422 || back.Code != "validation_failed" || back.Err != "ValidationError" ||
+		len(back.Details) != 1 || back.Details[0].Code != "required" || back.Meta["limit"] != "10" {
+		t.Errorf("Обратное преобразование потеряло данные: %+v", back)
+	}
+}
+
+func TestToStatus_Errors(t *testing.T) {
+	repoErr := &db.RepositoryError{Code: db.ErrCodeUniqueViolation, Reason: "already_exists", Err: errors.New("duplicate key")}
+	if st := ToStatus(repoErr, ""); st.Code() != codes.AlreadyExists || st.Message() != "Запись уже существует" {
+		t.Errorf("Ожидался AlreadyExists, а получили %s %q", st.Code(), st.Message())
+	}
+
+	st := ToStatus(errors.New("pq: password authentication failed"), "en")
+	if st.Code() != codes.Internal || st.Message() != "Internal server error" {
+		t.Errorf("Текст внутренней ошибки не должен уходить клиенту, а получили %s %q", st.Code(), st.Message())
+	}
+
+	if st := ToStatus(context.DeadlineExceeded, ""); st.Code() != codes.DeadlineExceeded {
+		t.Errorf("Ожидался DeadlineExceeded, а получили %s", st.Code())
+	}
+
+	original := status.Error(codes.ResourceExhausted, "slow down")
+	if st := ToStatus(original, ""); st.Code() != codes.ResourceExhausted || st.Message() != "slow down" {
+		t.Errorf("Статус gRPC должен возвращаться как есть, а получили %s %q", st.Code(), st.Message())
+	}
+}
+
+func TestFromError(t *testing.T) {
+	err := FromError(status.Error(codes.NotFound, "user 42 not found"))
+	var appErr *exc.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Ожидалась exc.Error, а получили %T", err)
+	}
+	if appErr.StatusCode != 404 || appErr.Code != "not_found" || appErr.Message != "user 42 not found" {
+		t.Errorf("Неверная ошибка из чужого статуса: %+v", appErr)
+	}
+	if status.Code(errors.Unwrap(appErr)) != codes.NotFound {
+		t.Errorf("Исходный статус должен быть доступен через Unwrap")
+	}
+
+	plain := errors.New("connection refused")
+	if FromError(plain) != plain || FromError(nil) != nil {
+		t.Errorf("Ошибки, не являющиеся статусом, должны возвращаться как есть")
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(InterceptorConfig{Locale: "ru"})
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, exc.NotFoundError("not_found", "")
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("accept-language", "en-US,en;q=0.9"))
+	_, err := interceptor(ctx, nil, info, handler)
+	st, _ := status.FromError(err)
+	if st.Code() != codes.NotFound || st.Message() != "Record not found" {
+		t.Errorf("Ожидался NotFound на английском, а получили %s %q", st.Code(), st.Message())
+	}
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	if msg := status.Convert(err).Message(); msg != "Запись не найдена" {
+		t.Errorf("Без accept-language ожидалось сообщение на языке Locale, а получили %q", msg)
+	}
+
+	resp, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	if err != nil || resp != "ok" {
+		t.Errorf("Успешный ответ не должен меняться: %v %v", resp, err)
+	}
+}